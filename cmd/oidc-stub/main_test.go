@@ -0,0 +1,203 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"ecommerce-api/domain"
+	"ecommerce-api/service"
+)
+
+const testRedirectURL = "http://shop.test/api/auth/oidc/stub/callback"
+
+// startStub serves a stub provider and returns its issuer URL.
+func startStub(t *testing.T) string {
+	t.Helper()
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	s, err := newStub(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux.Handle("/", s.routes())
+	return srv.URL
+}
+
+func newProvider(issuer, clientID string) *service.OIDCProvider {
+	return service.NewOIDCProvider(service.OIDCProviderConfig{
+		Name: "stub", Issuer: issuer, ClientID: clientID, RedirectURL: testRedirectURL,
+	})
+}
+
+// authorize signs in at the stub as subject and returns the code and state it
+// redirects back with.
+func authorize(t *testing.T, authURL, subject string) (string, string) {
+	t.Helper()
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(authURL + "&login_hint=" + url.QueryEscape(subject))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorize returned %d", resp.StatusCode)
+	}
+	back, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := back.Scheme + "://" + back.Host + back.Path; got != testRedirectURL {
+		t.Fatalf("redirected to %s", got)
+	}
+	return back.Query().Get("code"), back.Query().Get("state")
+}
+
+type memoryStates struct {
+	states map[string]*domain.OIDCLoginState
+}
+
+func (m *memoryStates) Create(state *domain.OIDCLoginState) error {
+	m.states[state.StateHash] = state
+	return nil
+}
+
+func (m *memoryStates) Consume(stateHash string) (*domain.OIDCLoginState, error) {
+	state, ok := m.states[stateHash]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	delete(m.states, stateHash)
+	return state, nil
+}
+
+// linkedIdentities knows one identity: subject "alice", linked to user 7.
+type linkedIdentities struct{ domain.IdentityRepository }
+
+func (linkedIdentities) FindByProviderSubject(provider, subject string) (*domain.Identity, error) {
+	if provider == "stub" && subject == "alice" {
+		return &domain.Identity{UserID: 7, Provider: provider, Subject: subject}, nil
+	}
+	return nil, domain.ErrNotFound
+}
+
+type knownUsers struct{ domain.UserRepository }
+
+func (knownUsers) FindByID(id uint) (*domain.User, error) {
+	user := &domain.User{Username: "alice"}
+	user.ID = id
+	return user, nil
+}
+
+func TestOIDCLoginFlow(t *testing.T) {
+	issuer := startStub(t)
+	svc := service.NewECommerceService(service.Dependencies{
+		Users:         knownUsers{},
+		Identities:    linkedIdentities{},
+		OIDCStates:    &memoryStates{states: map[string]*domain.OIDCLoginState{}},
+		OIDCProviders: []*service.OIDCProvider{newProvider(issuer, "ecommerce")},
+	})
+
+	authURL, state, err := svc.StartOIDCLogin("stub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, returnedState := authorize(t, authURL, "alice")
+	if returnedState != state {
+		t.Fatalf("state %q came back as %q", state, returnedState)
+	}
+
+	if _, err := svc.FinishOIDCLogin("stub", "forged-state", code); !errors.Is(err, domain.ErrInvalidToken) {
+		t.Errorf("unknown state: got %v, want ErrInvalidToken", err)
+	}
+	user, err := svc.FinishOIDCLogin("stub", state, code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.ID != 7 {
+		t.Errorf("logged in as user %d, want 7", user.ID)
+	}
+	if _, err := svc.FinishOIDCLogin("stub", state, code); !errors.Is(err, domain.ErrInvalidToken) {
+		t.Errorf("replayed state: got %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestOIDCExchangeChecks(t *testing.T) {
+	const verifier, nonce = "verifier-0123456789-0123456789-0123456789", "nonce-1"
+
+	t.Run("valid", func(t *testing.T) {
+		p := newProvider(startStub(t), "ecommerce")
+		authURL, _ := p.AuthCodeURL("state", nonce, verifier)
+		code, _ := authorize(t, authURL, "alice")
+		identity, err := p.Exchange(code, verifier, nonce)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if identity.Subject != "alice" || identity.Email != "alice@example.test" || !identity.EmailVerified {
+			t.Errorf("got identity %+v", identity)
+		}
+	})
+
+	t.Run("wrong nonce", func(t *testing.T) {
+		p := newProvider(startStub(t), "ecommerce")
+		authURL, _ := p.AuthCodeURL("state", nonce, verifier)
+		code, _ := authorize(t, authURL, "alice")
+		if _, err := p.Exchange(code, verifier, "nonce-2"); err == nil || !strings.Contains(err.Error(), "nonce") {
+			t.Errorf("got %v, want a nonce mismatch", err)
+		}
+	})
+
+	t.Run("wrong PKCE verifier", func(t *testing.T) {
+		p := newProvider(startStub(t), "ecommerce")
+		authURL, _ := p.AuthCodeURL("state", nonce, verifier)
+		code, _ := authorize(t, authURL, "alice")
+		if _, err := p.Exchange(code, "another-verifier", nonce); err == nil || !strings.Contains(err.Error(), "PKCE") {
+			t.Errorf("got %v, want a PKCE failure", err)
+		}
+	})
+
+	t.Run("token for another client", func(t *testing.T) {
+		issuer := startStub(t)
+		other := newProvider(issuer, "other-client")
+		authURL, _ := other.AuthCodeURL("state", nonce, verifier)
+		code, _ := authorize(t, authURL, "alice")
+		if _, err := newProvider(issuer, "ecommerce").Exchange(code, verifier, nonce); !errors.Is(err, jwt.ErrTokenInvalidAudience) {
+			t.Errorf("got %v, want an audience error", err)
+		}
+	})
+
+	t.Run("token from another issuer", func(t *testing.T) {
+		// Discovery names the test server as issuer, but the tokens claim another
+		mux := http.NewServeMux()
+		srv := httptest.NewServer(mux)
+		defer srv.Close()
+		honest, err := newStub(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		impostor, err := newStub("https://impostor.test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		mux.Handle("GET /.well-known/openid-configuration", honest.routes())
+		mux.Handle("/", impostor.routes())
+
+		p := newProvider(srv.URL, "ecommerce")
+		authURL, err := p.AuthCodeURL("state", nonce, verifier)
+		if err != nil {
+			t.Fatal(err)
+		}
+		code, _ := authorize(t, authURL, "alice")
+		if _, err := p.Exchange(code, verifier, nonce); !errors.Is(err, jwt.ErrTokenInvalidIssuer) {
+			t.Errorf("got %v, want an issuer error", err)
+		}
+	})
+}
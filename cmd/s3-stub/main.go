@@ -0,0 +1,192 @@
+// Command s3-stub is a minimal S3-compatible object store for local development
+// and testing of S3 image storage. It keeps objects in memory, supports
+// path-style PUT, GET, HEAD and DELETE, checks Signature Version 4 on writes
+// and serves reads anonymously; never expose it outside a test setup.
+//
+//	go run ./cmd/s3-stub
+//	IMAGE_STORAGE=s3 S3_ENDPOINT=http://localhost:9000 S3_PATH_STYLE=true S3_BUCKET=images \
+//	S3_ACCESS_KEY_ID=stub S3_SECRET_ACCESS_KEY=stub-secret go run .
+//
+// Any bucket name is accepted. STUB_ACCESS_KEY_ID and STUB_SECRET_ACCESS_KEY
+// change the expected credentials.
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+type object struct {
+	contentType string
+	data        []byte
+}
+
+type stub struct {
+	accessKeyID     string
+	secretAccessKey string
+
+	mu      sync.Mutex
+	objects map[string]object
+}
+
+func main() {
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "9000"
+	}
+	s := &stub{
+		accessKeyID:     envOr("STUB_ACCESS_KEY_ID", "stub"),
+		secretAccessKey: envOr("STUB_SECRET_ACCESS_KEY", "stub-secret"),
+		objects:         map[string]object{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("PUT /{bucket}/{key...}", s.put)
+	mux.HandleFunc("GET /{bucket}/{key...}", s.get)
+	mux.HandleFunc("DELETE /{bucket}/{key...}", s.delete)
+
+	log.Printf("Stub S3 listening on port %s (access key %q)", port, s.accessKeyID)
+	log.Fatal(http.ListenAndServe(":"+port, mux))
+}
+
+func (s *stub) put(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "IncompleteBody", err.Error())
+		return
+	}
+	if !s.authorized(w, r, body) {
+		return
+	}
+	s.mu.Lock()
+	s.objects[r.URL.Path] = object{contentType: r.Header.Get("Content-Type"), data: body}
+	s.mu.Unlock()
+	w.WriteHeader(http.StatusOK)
+}
+
+// get also answers HEAD requests.
+func (s *stub) get(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	obj, ok := s.objects[r.URL.Path]
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, "NoSuchKey", "The specified key does not exist.")
+		return
+	}
+	if obj.contentType != "" {
+		w.Header().Set("Content-Type", obj.contentType)
+	}
+	w.Header().Set("Content-Length", fmt.Sprint(len(obj.data)))
+	w.WriteHeader(http.StatusOK)
+	if r.Method != http.MethodHead {
+		w.Write(obj.data)
+	}
+}
+
+func (s *stub) delete(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(w, r, nil) {
+		return
+	}
+	s.mu.Lock()
+	delete(s.objects, r.URL.Path)
+	s.mu.Unlock()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// authorized verifies the request's Signature Version 4 header signature,
+// writing an S3-style error if it does not match.
+func (s *stub) authorized(w http.ResponseWriter, r *http.Request, body []byte) bool {
+	const algorithm = "AWS4-HMAC-SHA256 "
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, algorithm) {
+		writeError(w, http.StatusForbidden, "AccessDenied", "Missing Signature Version 4 authorization.")
+		return false
+	}
+	fields := map[string]string{}
+	for _, part := range strings.Split(strings.TrimPrefix(auth, algorithm), ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		fields[name] = value
+	}
+	credential := strings.SplitN(fields["Credential"], "/", 2)
+	if len(credential) != 2 || credential[0] != s.accessKeyID {
+		writeError(w, http.StatusForbidden, "InvalidAccessKeyId", "The access key ID does not exist.")
+		return false
+	}
+	scope := credential[1] // date/region/service/aws4_request
+	scopeParts := strings.Split(scope, "/")
+	if len(scopeParts) != 4 {
+		writeError(w, http.StatusBadRequest, "AuthorizationHeaderMalformed", "Malformed credential scope.")
+		return false
+	}
+
+	payloadHash := sha256Hex(body)
+	if r.Header.Get("X-Amz-Content-Sha256") != payloadHash {
+		writeError(w, http.StatusBadRequest, "XAmzContentSHA256Mismatch", "The payload hash does not match.")
+		return false
+	}
+
+	signedHeaders := strings.Split(fields["SignedHeaders"], ";")
+	sort.Strings(signedHeaders)
+	var canonicalHeaders strings.Builder
+	for _, name := range signedHeaders {
+		value := r.Header.Get(name)
+		if name == "host" {
+			value = r.Host
+		}
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+	canonicalRequest := strings.Join([]string{
+		r.Method,
+		r.URL.EscapedPath(),
+		r.URL.RawQuery,
+		canonicalHeaders.String(),
+		strings.Join(signedHeaders, ";"),
+		payloadHash,
+	}, "\n")
+	amzDate := r.Header.Get("X-Amz-Date")
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := []byte("AWS4" + s.secretAccessKey)
+	for _, part := range scopeParts {
+		key = hmacSHA256(key, part)
+	}
+	expected := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	if !hmac.Equal([]byte(expected), []byte(fields["Signature"])) {
+		writeError(w, http.StatusForbidden, "SignatureDoesNotMatch", "The request signature does not match.")
+		return false
+	}
+	return true
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<Error><Code>%s</Code><Message>%s</Message></Error>", code, message)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
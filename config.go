@@ -3,6 +3,11 @@ package main
 import (
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"ecommerce-api/service"
 )
 
 type Config struct {
@@ -12,10 +17,41 @@ type Config struct {
 	DBName		string
 	DBPort		string
 	JWTSecret	string
+	JWTKeysDir	string
+	JWTActiveKeyID	string
+	JWTAcceptLegacyTokens	bool
 	StripeKey	string
+	StripeWebhookSecret	string
+	PaymentProvider	string
+	FakePaymentScenario	string
 	Port		string
 	AdminUser	string
 	AdminPass	string
+	PasswordHashAlgorithm	string
+	ReservationTTL	time.Duration
+	ReservationSweepInterval	time.Duration
+	IdempotencyKeyTTL	time.Duration
+	AccessTokenTTL	time.Duration
+	RefreshTokenTTL	time.Duration
+	PasswordResetTTL	time.Duration
+	EmailVerificationTTL	time.Duration
+	RequireVerifiedEmail	bool
+	RequireAdminMFA	bool
+	MFAIssuer	string
+	LoginMaxFailures	int
+	LoginIPMaxFailures	int
+	LoginBaseDelay	time.Duration
+	LoginLockout	time.Duration
+	TrustProxyHeaders	bool
+	OIDCProviders	[]service.OIDCProviderConfig
+	ImageStorage	string
+	ImageDir	string
+	ImageBaseURL	string
+	MaxImageBytes	int64
+	S3	service.S3Config
+	DevMode	bool
+	Notifier	string
+	SMTP	service.SMTPConfig
 }
 
 func LoadConfig() Config {
@@ -27,10 +63,35 @@ func LoadConfig() Config {
 		DBName:     os.Getenv("DB_NAME"),
 		DBPort:     os.Getenv("DB_PORT"),
 		JWTSecret:  os.Getenv("JWT_SECRET"),
+		JWTKeysDir: os.Getenv("JWT_KEYS_DIR"),
+		JWTActiveKeyID: os.Getenv("JWT_ACTIVE_KID"),
 		StripeKey:  os.Getenv("STRIPE_SECRET_KEY"),
+		StripeWebhookSecret: os.Getenv("STRIPE_WEBHOOK_SECRET"),
+		PaymentProvider: os.Getenv("PAYMENT_PROVIDER"),
+		FakePaymentScenario: os.Getenv("FAKE_PAYMENT_SCENARIO"),
 		Port:       os.Getenv("PORT"),
 		AdminUser:  os.Getenv("ADMIN_USER"),
 		AdminPass:  os.Getenv("ADMIN_PASS"),
+		PasswordHashAlgorithm: os.Getenv("PASSWORD_HASH_ALGORITHM"),
+		MFAIssuer: os.Getenv("MFA_ISSUER"),
+		ImageStorage: os.Getenv("IMAGE_STORAGE"),
+		ImageDir: os.Getenv("IMAGE_DIR"),
+		ImageBaseURL: os.Getenv("IMAGE_BASE_URL"),
+		S3: service.S3Config{
+			Endpoint:        os.Getenv("S3_ENDPOINT"),
+			Region:          os.Getenv("S3_REGION"),
+			Bucket:          os.Getenv("S3_BUCKET"),
+			AccessKeyID:     os.Getenv("S3_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+			PublicURL:       os.Getenv("S3_PUBLIC_URL"),
+		},
+		Notifier: os.Getenv("NOTIFIER"),
+		SMTP: service.SMTPConfig{
+			Host:     os.Getenv("SMTP_HOST"),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     os.Getenv("SMTP_FROM"),
+		},
 	}
 
 
@@ -40,13 +101,100 @@ func LoadConfig() Config {
 	if cfg.DBName == "" { cfg.DBName = "ecommerce_db" }
 	if cfg.DBPort == "" { cfg.DBPort = "5432" }
 	if cfg.JWTSecret == "" { cfg.JWTSecret = "a_highly_secured_secret_for_jwt_signing_1234567890" }
-	if cfg.StripeKey == "" { 
-		cfg.StripeKey = "sk_test_mocked_for_development_12345" 
-	}
+	// The fake gateway must be asked for by name, so a deploy missing its Stripe key fails instead of taking fake payments
+	if cfg.PaymentProvider == "" { cfg.PaymentProvider = "stripe" }
+	if cfg.FakePaymentScenario == "" { cfg.FakePaymentScenario = "success" }
 	if cfg.Port == "" { cfg.Port = "8080" }
 	if cfg.AdminUser == "" { cfg.AdminUser = "ecommerce_admin" }
 	if cfg.AdminPass == "" { cfg.AdminPass = "SuperSecureAdminPass123" }
+	if cfg.PasswordHashAlgorithm == "" { cfg.PasswordHashAlgorithm = "argon2id" }
+	if cfg.MFAIssuer == "" { cfg.MFAIssuer = "E-Commerce API" }
+	if cfg.ImageStorage == "" { cfg.ImageStorage = "local" }
+	if cfg.ImageDir == "" { cfg.ImageDir = "uploads" }
+	if cfg.ImageBaseURL == "" { cfg.ImageBaseURL = "/media" }
+	if cfg.Notifier == "" { cfg.Notifier = "smtp" }
+	cfg.ReservationTTL = durationEnv("RESERVATION_TTL", 15*time.Minute)
+	cfg.ReservationSweepInterval = durationEnv("RESERVATION_SWEEP_INTERVAL", time.Minute)
+	cfg.IdempotencyKeyTTL = durationEnv("IDEMPOTENCY_KEY_TTL", 24*time.Hour)
+	cfg.AccessTokenTTL = durationEnv("ACCESS_TOKEN_TTL", 15*time.Minute)
+	cfg.RefreshTokenTTL = durationEnv("REFRESH_TOKEN_TTL", 30*24*time.Hour)
+	cfg.PasswordResetTTL = durationEnv("PASSWORD_RESET_TTL", time.Hour)
+	cfg.EmailVerificationTTL = durationEnv("EMAIL_VERIFICATION_TTL", 24*time.Hour)
+	cfg.RequireVerifiedEmail = boolEnv("REQUIRE_VERIFIED_EMAIL", false)
+	cfg.RequireAdminMFA = boolEnv("REQUIRE_ADMIN_MFA", false)
+	cfg.JWTAcceptLegacyTokens = boolEnv("JWT_ACCEPT_LEGACY_TOKENS", false)
+	cfg.LoginMaxFailures = intEnv("LOGIN_MAX_FAILURES", 5)
+	cfg.LoginIPMaxFailures = intEnv("LOGIN_IP_MAX_FAILURES", 50)
+	cfg.LoginBaseDelay = durationEnv("LOGIN_BASE_DELAY", time.Second)
+	cfg.LoginLockout = durationEnv("LOGIN_LOCKOUT", 15*time.Minute)
+	cfg.TrustProxyHeaders = boolEnv("TRUST_PROXY_HEADERS", false)
+	cfg.OIDCProviders = oidcProvidersEnv()
+	cfg.MaxImageBytes = int64(intEnv("MAX_IMAGE_BYTES", 10<<20))
+	cfg.S3.PathStyle = boolEnv("S3_PATH_STYLE", false)
+	cfg.DevMode = boolEnv("DEV_MODE", false)
+	cfg.SMTP.Port = intEnv("SMTP_PORT", 587)
 
 	log.Println("Configuration loaded.")
 	return cfg
-}
\ No newline at end of file
+}
+
+// durationEnv reads a Go duration such as "15m" from the environment.
+func durationEnv(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" { return fallback }
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		log.Printf("Invalid %s %q, using %s", key, value, fallback)
+		return fallback
+	}
+	return d
+}
+
+// boolEnv reads a boolean such as "true" or "1" from the environment.
+func boolEnv(key string, fallback bool) bool {
+	value := os.Getenv(key)
+	if value == "" { return fallback }
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("Invalid %s %q, using %t", key, value, fallback)
+		return fallback
+	}
+	return b
+}
+
+// intEnv reads a non-negative integer from the environment.
+func intEnv(key string, fallback int) int {
+	value := os.Getenv(key)
+	if value == "" { return fallback }
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		log.Printf("Invalid %s %q, using %d", key, value, fallback)
+		return fallback
+	}
+	return n
+}
+
+// oidcProvidersEnv reads OIDC_PROVIDERS=google,stub and, for each name,
+// OIDC_<NAME>_ISSUER, _CLIENT_ID, _CLIENT_SECRET, _REDIRECT_URL and _SCOPES.
+func oidcProvidersEnv() []service.OIDCProviderConfig {
+	var providers []service.OIDCProviderConfig
+	for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" { continue }
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		p := service.OIDCProviderConfig{
+			Name:         name,
+			Issuer:       os.Getenv(prefix + "ISSUER"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			RedirectURL:  os.Getenv(prefix + "REDIRECT_URL"),
+			Scopes:       strings.Fields(os.Getenv(prefix + "SCOPES")),
+		}
+		if p.Issuer == "" || p.ClientID == "" || p.RedirectURL == "" {
+			log.Printf("Skipping OIDC provider %q: %sISSUER, %sCLIENT_ID and %sREDIRECT_URL are required", name, prefix, prefix, prefix)
+			continue
+		}
+		providers = append(providers, p)
+	}
+	return providers
+}
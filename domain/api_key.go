@@ -0,0 +1,32 @@
+package domain
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// APIKey is a long-lived credential for server-to-server integrations. The key
+// is "eck_<prefix>_<secret>"; the prefix is stored in clear to find and identify
+// the key, the full key only as a SHA-256 hash.
+type APIKey struct {
+	gorm.Model
+	Name        string       `gorm:"not null"`
+	Prefix      string       `gorm:"uniqueIndex;not null"`
+	KeyHash     string       `gorm:"not null"`
+	Scopes      []Permission `gorm:"serializer:json;not null"`
+	CreatedByID uint         `gorm:"index;not null"` // Requests are attributed to this user
+	ExpiresAt   *time.Time
+	LastUsedAt  *time.Time
+	RevokedAt   *time.Time
+}
+
+type APIKeyRepository interface {
+	Create(key *APIKey) error
+	FindAll() ([]APIKey, error)
+	FindByPrefix(prefix string) (*APIKey, error)
+	// Revoke reports false if the key does not exist or was already revoked.
+	Revoke(id uint) (bool, error)
+	// TouchLastUsed records use, at most once a minute per key to spare writes.
+	TouchLastUsed(id uint, at time.Time) error
+}
@@ -8,6 +8,8 @@ type CartItem struct {
 	gorm.Model
 	CartID    uint
 	ProductID uint
+	VariantID uint `gorm:"index"`
+	SKU       string
 	Quantity  int `gorm:"default:1"`
 	Name string 
 	PriceCents int64
@@ -3,7 +3,11 @@ package domain
 
 type CartRepository interface {
 	FindByUserID(userID uint) (*Cart, error)
+	// FindByUserIDForUpdate loads the user's cart with its items and locks the
+	// cart row until the transaction ends, or fails with ErrNotFound if the user
+	// has none yet. Use it inside UnitOfWork.Do.
+	FindByUserIDForUpdate(userID uint) (*Cart, error)
 	Save(cart *Cart) error
 	Clear(userID uint) error
-	UpdateInventory(productID uint, quantityChange int) error // For managing inventory during checkout
+	UpdateInventory(variantID uint, quantityChange int, reason MovementReason, reference string) error // Records the change in the inventory ledger; ErrNotFound if the variant was deleted
 }
\ No newline at end of file
@@ -0,0 +1,25 @@
+package domain
+
+import (
+	"gorm.io/gorm"
+)
+
+// Category is a node of the catalog taxonomy. Root categories have no parent.
+type Category struct {
+	gorm.Model
+	Name     string     `gorm:"not null"`
+	Slug     string     `gorm:"uniqueIndex;not null"` // URL-safe name, e.g. "running-shoes"
+	ParentID *uint      `gorm:"index"`
+	Children []Category `gorm:"foreignKey:ParentID"` // Only filled in when building the tree
+}
+
+type CategoryRepository interface {
+	Create(category *Category) error
+	FindAll() ([]Category, error)
+	FindByID(id uint) (*Category, error)
+	FindByIDs(ids []uint) ([]Category, error)
+	FindBySlug(slug string) (*Category, error)
+	Update(category *Category) error
+	// Delete removes the category and its product assignments.
+	Delete(id uint) error
+}
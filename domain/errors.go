@@ -9,4 +9,46 @@ var (
 	ErrInsufficientInv 		= errors.New("insufficient product inventory")
 	ErrCartEmpty			= errors.New("cannot checkout empty cart")
 	ErrInvalidCredentials	= errors.New("invalid username or password")
+	ErrInvalidSignature		= errors.New("invalid webhook signature")
+	ErrPaymentDeclined		= errors.New("payment was declined")
+	ErrPaymentTimeout		= errors.New("payment provider timed out")
+	ErrCheckoutFailed		= errors.New("the checkout with this Idempotency-Key already failed; retry with a new key")
+	ErrNotRefundable		= errors.New("order cannot be refunded in its current status")
+	ErrInvalidRefund		= errors.New("invalid refund request")
+	ErrInvalidAdjustment	= errors.New("invalid inventory adjustment")
+	ErrInvalidToken			= errors.New("invalid or expired token")
+	ErrTokenReused			= errors.New("refresh token was already used; all sessions in its family were revoked")
+	ErrUnknownRole			= errors.New("unknown role")
+	ErrRoleNotGrantable		= errors.New("cannot grant or revoke a role with permissions you do not hold")
+	ErrInvalidPassword		= errors.New("password cannot be empty")
+	ErrInvalidEmail			= errors.New("invalid email address")
+	ErrEmailTaken			= errors.New("email address is already in use")
+	ErrEmailRequired		= errors.New("no email address on the account")
+	ErrEmailAlreadyVerified	= errors.New("email address is already verified")
+	ErrEmailNotVerified		= errors.New("email address must be verified before checkout")
+	ErrInvalidMFACode		= errors.New("invalid authentication code")
+	ErrMFAAlreadyEnabled	= errors.New("two-factor authentication is already enabled")
+	ErrMFANotEnrolled		= errors.New("two-factor authentication is not set up")
+	ErrTooManyAttempts		= errors.New("too many failed login attempts")
+	ErrUnknownProvider		= errors.New("unknown identity provider")
+	ErrExternalLogin		= errors.New("external login failed")
+	ErrInvalidAPIKey		= errors.New("invalid, expired or revoked API key")
+	ErrInvalidAPIKeyRequest	= errors.New("invalid API key request")
+	ErrInvalidProduct		= errors.New("invalid product")
+	ErrVersionConflict		= errors.New("the record was changed by someone else; reload it and try again")
+	ErrInvalidQuery			= errors.New("invalid query")
+	ErrInvalidCategory		= errors.New("invalid category")
+	ErrCategorySlugTaken	= errors.New("category slug is already in use")
+	ErrCategoryHasChildren	= errors.New("category has subcategories; move or delete them first")
+	ErrInvalidVariant		= errors.New("invalid product variant")
+	ErrVariantRequired		= errors.New("product has several variants; choose one with variant_id")
+	ErrSKUTaken				= errors.New("SKU is already in use")
+	ErrVariantHasStock		= errors.New("variant still has stock on hand or reserved; adjust it to zero first")
+	ErrLastVariant			= errors.New("a product must keep at least one variant")
+	ErrOptionTypeTaken		= errors.New("option type already exists")
+	ErrInvalidImage			= errors.New("invalid image")
+	ErrUnsupportedImage		= errors.New("unsupported image type; upload a JPEG, PNG or GIF")
+	ErrImageTooLarge		= errors.New("image is too large")
+	ErrTooManyImages		= errors.New("product has the maximum number of images")
+	ErrInvalidImport		= errors.New("invalid import file")
 )
\ No newline at end of file
@@ -0,0 +1,36 @@
+package domain
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// IdempotencyRecord remembers a mutating request made with an Idempotency-Key
+// header, so a retry can be answered with the original response.
+type IdempotencyRecord struct {
+	gorm.Model
+	Key          string `gorm:"uniqueIndex:idx_idempotency_scope;not null"`
+	UserID       uint   `gorm:"uniqueIndex:idx_idempotency_scope"` // 0 for unauthenticated requests
+	Method       string `gorm:"not null"`
+	Path         string `gorm:"not null"`
+	RequestHash  string `gorm:"not null"` // SHA-256 of method, path and body
+	StatusCode   int
+	ContentType  string
+	ResponseBody []byte
+	CompletedAt  *time.Time // nil while the original request is still running
+}
+
+type IdempotencyRepository interface {
+	// Reserve stores record as in flight. If the key is already taken for the
+	// user it returns the existing record and stores nothing, unless that record
+	// is still in flight and was created before staleBefore: its request died
+	// without releasing the key, so record takes its place.
+	Reserve(record *IdempotencyRecord, staleBefore time.Time) (*IdempotencyRecord, error)
+	Complete(record *IdempotencyRecord) error
+	// Release forgets the key so the request can be retried.
+	Release(record *IdempotencyRecord) error
+	// DeleteCreatedBefore forgets the keys created before the given time,
+	// completed or not, and returns how many were deleted.
+	DeleteCreatedBefore(before time.Time) (int64, error)
+}
@@ -0,0 +1,39 @@
+package domain
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Identity links an account at an external OpenID Connect provider to a User.
+type Identity struct {
+	gorm.Model
+	UserID   uint   `gorm:"index;not null"`
+	Provider string `gorm:"uniqueIndex:idx_identity_subject;not null"`
+	Subject  string `gorm:"uniqueIndex:idx_identity_subject;not null"` // The provider's stable "sub" claim
+	Email    string // As reported by the provider at link time
+}
+
+// OIDCLoginState carries an OIDC login from start to callback. It is keyed by
+// the hash of the "state" parameter and deleted when the callback redeems it.
+type OIDCLoginState struct {
+	ID           uint `gorm:"primarykey"`
+	CreatedAt    time.Time
+	StateHash    string    `gorm:"uniqueIndex;not null"`
+	Provider     string    `gorm:"not null"`
+	Nonce        string    `gorm:"not null"`
+	CodeVerifier string    `gorm:"not null"` // PKCE secret; only its hash went to the provider
+	ExpiresAt    time.Time `gorm:"index;not null"`
+}
+
+type IdentityRepository interface {
+	FindByProviderSubject(provider, subject string) (*Identity, error)
+	Create(identity *Identity) error
+}
+
+type OIDCStateRepository interface {
+	Create(state *OIDCLoginState) error
+	// Consume deletes and returns the state, so it can be redeemed only once.
+	Consume(stateHash string) (*OIDCLoginState, error)
+}
@@ -0,0 +1,48 @@
+package domain
+
+import (
+	"time"
+)
+
+// MovementReason explains why a variant's stock changed.
+type MovementReason string
+
+const (
+	MovementSale               MovementReason = "sale"                // Reserved stock taken after a successful payment
+	MovementRefundRestock      MovementReason = "refund_restock"      // Refunded units put back on the shelf
+	MovementAdjustment         MovementReason = "adjustment"          // Manual correction, e.g. after a stock count
+	MovementReceiving          MovementReason = "receiving"           // New stock arrived
+	MovementReservation        MovementReason = "reservation"         // Stock held for a pending checkout
+	MovementReservationRelease MovementReason = "reservation_release" // Held stock given back
+)
+
+// IsManual reports whether staff may record this reason through the admin API.
+func (r MovementReason) IsManual() bool {
+	return r == MovementAdjustment || r == MovementReceiving
+}
+
+// InventoryMovement is an append-only ledger entry for one stock change. Rows are
+// never updated or deleted, so it has no UpdatedAt/DeletedAt.
+type InventoryMovement struct {
+	ID             uint           `gorm:"primarykey"`
+	CreatedAt      time.Time      `gorm:"index"`
+	ProductID      uint           `gorm:"index;not null"`
+	VariantID      uint           `gorm:"index"`
+	Reason         MovementReason `gorm:"type:varchar(32);not null"`
+	InventoryDelta int            `gorm:"not null"` // Change to units on hand
+	ReservedDelta  int            `gorm:"not null"` // Change to units held for checkouts
+	InventoryAfter int            `gorm:"not null"` // Balances of the variant
+	ReservedAfter  int            `gorm:"not null"`
+	Reference      string         `gorm:"index"` // What caused it, e.g. "order:12" or "refund:3"
+	Note           string
+	ActorID        *uint // Staff member for manual movements
+}
+
+type InventoryRepository interface {
+	// Record applies the movement's deltas to the variant and its product's
+	// totals and appends it to the ledger, failing with ErrInsufficientInv if
+	// stock on hand would drop below what is reserved, or ErrNotFound if the
+	// variant was deleted. ProductID is filled in from the variant.
+	Record(movement *InventoryMovement) error
+	FindByProductID(productID uint, limit int) ([]InventoryMovement, error)
+}
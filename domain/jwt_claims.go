@@ -5,7 +5,21 @@ import (
 )
 
 type Claims struct {
-	UserID uint `json:"user_id"`
-	IsAdmin bool `json:"is_admin"`
+	UserID      uint         `json:"user_id"`
+	Roles       []string     `json:"roles,omitempty"`
+	Permissions []Permission `json:"permissions,omitempty"`
+	MFA         bool         `json:"mfa,omitempty"`        // The session passed a second factor
+	Purpose     string       `json:"purpose,omitempty"`    // Empty for access tokens
+	APIKeyID    uint         `json:"api_key_id,omitempty"` // Set when the request used an API key
 	jwt.RegisteredClaims
-}
\ No newline at end of file
+}
+
+// HasPermission reports whether the token grants p.
+func (c *Claims) HasPermission(p Permission) bool {
+	for _, granted := range c.Permissions {
+		if granted == p || granted == PermissionAll {
+			return true
+		}
+	}
+	return false
+}
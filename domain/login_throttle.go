@@ -0,0 +1,38 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// LoginThrottle counts recent failed logins for one subject: a username
+// ("user:alice") or a client IP ("ip:203.0.113.7").
+type LoginThrottle struct {
+	Subject       string    `gorm:"primaryKey"`
+	Failures      int       `gorm:"not null"`
+	LastFailureAt time.Time `gorm:"not null"`
+	BlockedUntil  *time.Time
+}
+
+type LoginThrottleRepository interface {
+	Find(subjects []string) ([]LoginThrottle, error)
+	// RecordFailure counts a failure and returns the new total. Failures before
+	// windowStart are forgotten.
+	RecordFailure(subject string, now, windowStart time.Time) (int, error)
+	Block(subject string, until time.Time) error
+	Reset(subject string) error
+}
+
+// ThrottledError is returned while a subject is blocked. It matches
+// ErrTooManyAttempts with errors.Is.
+type ThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *ThrottledError) Error() string {
+	return fmt.Sprintf("%s; retry in %s", ErrTooManyAttempts, e.RetryAfter.Round(time.Second))
+}
+
+func (e *ThrottledError) Is(target error) bool {
+	return target == ErrTooManyAttempts
+}
@@ -0,0 +1,23 @@
+package domain
+
+import (
+	"time"
+)
+
+// RecoveryCode is a single-use fallback for a lost authenticator. Only the
+// SHA-256 hash of the code is stored.
+type RecoveryCode struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	UserID    uint   `gorm:"index;not null"`
+	CodeHash  string `gorm:"uniqueIndex;not null"`
+	UsedAt    *time.Time
+}
+
+type RecoveryCodeRepository interface {
+	// ReplaceForUser drops the user's existing codes and stores the new hashes.
+	ReplaceForUser(userID uint, codeHashes []string) error
+	// Use redeems an unused code and reports whether one matched.
+	Use(userID uint, codeHash string) (bool, error)
+	DeleteForUser(userID uint) error
+}
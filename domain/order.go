@@ -0,0 +1,61 @@
+package domain
+
+import (
+	"gorm.io/gorm"
+)
+
+// OrderStatus tracks where an order is in the payment lifecycle.
+type OrderStatus string
+
+const (
+	OrderStatusPending           OrderStatus = "pending"
+	OrderStatusPaid              OrderStatus = "paid"
+	OrderStatusFailed            OrderStatus = "failed"
+	OrderStatusPartiallyRefunded OrderStatus = "partially_refunded"
+	OrderStatusRefunded          OrderStatus = "refunded"
+)
+
+// orderTransitions lists the statuses an order may move to from each status.
+var orderTransitions = map[OrderStatus][]OrderStatus{
+	OrderStatusPending:           {OrderStatusPaid, OrderStatusFailed},
+	OrderStatusPaid:              {OrderStatusPartiallyRefunded, OrderStatusRefunded},
+	OrderStatusPartiallyRefunded: {OrderStatusPartiallyRefunded, OrderStatusRefunded},
+}
+
+// CanTransitionTo reports whether an order in status s may move to next.
+func (s OrderStatus) CanTransitionTo(next OrderStatus) bool {
+	for _, allowed := range orderTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+type OrderItem struct {
+	gorm.Model
+	OrderID    uint `gorm:"index;not null"`
+	ProductID  uint `gorm:"not null"`
+	VariantID  uint `gorm:"index"`
+	SKU        string
+	Name       string `gorm:"not null"` // Includes the variant's options
+	PriceCents int64  `gorm:"not null"` // Unit price at the time of purchase
+	Quantity   int    `gorm:"not null"`
+	// RefundedQuantity is how many of Quantity have been refunded so far
+	RefundedQuantity int `gorm:"not null;default:0"`
+}
+
+type Order struct {
+	gorm.Model
+	UserID          uint        `gorm:"index;uniqueIndex:idx_order_idempotency;not null"`
+	Status          OrderStatus `gorm:"type:varchar(32);not null;default:'pending'"`
+	TotalCents      int64       `gorm:"not null"`
+	Currency        string      `gorm:"type:varchar(3);not null;default:'usd'"`
+	PaymentIntentID string      `gorm:"index"`
+	RefundedCents   int64       `gorm:"not null;default:0"`
+	// IdempotencyKey is the client's Idempotency-Key for the checkout, so a
+	// retry resumes this order instead of placing another
+	IdempotencyKey *string `gorm:"uniqueIndex:idx_order_idempotency"`
+	Items          []OrderItem
+	Refunds        []Refund
+}
@@ -0,0 +1,14 @@
+package domain
+
+type OrderRepository interface {
+	Create(order *Order) error
+	FindByID(id uint) (*Order, error)
+	// FindByIDForUpdate loads the order like FindByID and locks its row until
+	// the transaction ends. Use it inside UnitOfWork.Do.
+	FindByIDForUpdate(id uint) (*Order, error)
+	FindByUserID(userID uint) ([]Order, error)
+	FindByPaymentIntentID(paymentIntentID string) (*Order, error)
+	FindByIdempotencyKey(userID uint, key string) (*Order, error)
+	Update(order *Order) error
+	UpdateItem(item *OrderItem) error
+}
@@ -0,0 +1,10 @@
+package domain
+
+// PasswordHasher turns passwords into self-describing encoded hashes (the
+// algorithm and its parameters are part of the string) and checks them.
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+	// Verify reports whether password matches encoded, and whether encoded should
+	// be replaced with a fresh Hash because its algorithm or parameters are outdated.
+	Verify(password string, encoded string) (ok bool, needsRehash bool, err error)
+}
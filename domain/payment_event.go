@@ -0,0 +1,36 @@
+package domain
+
+import (
+	"gorm.io/gorm"
+)
+
+// PaymentEventType is the provider-neutral kind of a payment notification.
+type PaymentEventType string
+
+const (
+	PaymentEventSucceeded PaymentEventType = "payment_succeeded"
+	PaymentEventFailed    PaymentEventType = "payment_failed"
+	PaymentEventRefunded  PaymentEventType = "payment_refunded"
+	PaymentEventIgnored   PaymentEventType = "ignored" // Verified, but not something we act on
+)
+
+// PaymentEvent is a verified notification from the payment provider.
+type PaymentEvent struct {
+	ID              string // Provider event ID, used to deduplicate retries
+	Type            PaymentEventType
+	ProviderType    string // Raw event type as sent by the provider
+	PaymentIntentID string
+	OrderID         uint // From the payment intent metadata, 0 if absent
+}
+
+// ProcessedEvent records a provider event that has already been handled.
+type ProcessedEvent struct {
+	gorm.Model
+	EventID string `gorm:"uniqueIndex;not null"`
+	Type    string
+}
+
+type ProcessedEventRepository interface {
+	// MarkProcessed records the event and reports false if it was already recorded.
+	MarkProcessed(eventID string, eventType string) (bool, error)
+}
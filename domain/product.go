@@ -6,8 +6,35 @@ import (
 
 type Product struct {
 	gorm.Model
-	Name        string  `gorm:"not null"`
+	Name        string  `gorm:"not null;index"`
 	Description string
-	PriceCents  int64   `gorm:"not null"` // Price stored in smallest currency unit (cents)
-	Inventory   int     `gorm:"default:0"`
+	PriceCents  int64   `gorm:"not null;index"` // Price stored in smallest currency unit (cents)
+	Inventory   int     `gorm:"default:0"` // Units on hand, summed over the variants
+	Reserved    int     `gorm:"not null;default:0"` // Units held for pending checkouts, summed over the variants
+	Version     int     `gorm:"not null;default:1"` // Bumped by every catalog edit, for optimistic concurrency
+	Categories  []Category `gorm:"many2many:product_categories"`
+	Variants    []ProductVariant
+	Images      []ProductImage // Ordered by position
+}
+
+// ProductChanges lists the catalog fields to update; nil fields are left as they are.
+type ProductChanges struct {
+	Name        *string
+	Description *string
+	PriceCents  *int64
+}
+
+// Available is the stock that can still be added to carts and checked out.
+func (p *Product) Available() int {
+	return p.Inventory - p.Reserved
+}
+
+// Variant returns the product's variant with the given ID. Variants must be preloaded.
+func (p *Product) Variant(id uint) *ProductVariant {
+	for i := range p.Variants {
+		if p.Variants[i].ID == id {
+			return &p.Variants[i]
+		}
+	}
+	return nil
 }
\ No newline at end of file
@@ -0,0 +1,31 @@
+package domain
+
+import (
+	"gorm.io/gorm"
+)
+
+// ProductImage is a picture of a product. The original upload and its resized
+// thumbnails live in blob storage under the stored keys.
+type ProductImage struct {
+	gorm.Model
+	ProductID   uint `gorm:"index;not null"`
+	Position    int  `gorm:"not null;default:0"` // Display order within the product, starting at 0
+	AltText     string
+	ContentType string `gorm:"not null"`
+	SizeBytes   int64  `gorm:"not null"`
+	Width       int    `gorm:"not null"`
+	Height      int    `gorm:"not null"`
+	Key         string `gorm:"not null"` // Original upload
+	SmallKey    string `gorm:"not null"` // Thumbnail for listings
+	MediumKey   string `gorm:"not null"` // Thumbnail for product pages
+}
+
+type ProductImageRepository interface {
+	Create(image *ProductImage) error
+	FindByProductID(productID uint) ([]ProductImage, error)
+	// UpdateAltText saves the image's alt text.
+	UpdateAltText(image *ProductImage) error
+	// SetPositions numbers the product's images in the given order.
+	SetPositions(productID uint, imageIDs []uint) error
+	Delete(id uint) error
+}
@@ -1,9 +1,66 @@
 package domain
 
+// ProductSort is the order of a product listing. Every order breaks ties by ID
+// so cursors are stable.
+type ProductSort string
+
+const (
+	SortRelevance ProductSort = "relevance" // Best search match first; needs a search
+	SortNewest    ProductSort = "newest"
+	SortPriceAsc  ProductSort = "price_asc"
+	SortPriceDesc ProductSort = "price_desc"
+	SortNameAsc   ProductSort = "name_asc"
+	SortNameDesc  ProductSort = "name_desc"
+)
+
+func (s ProductSort) Valid() bool {
+	switch s {
+	case SortRelevance, SortNewest, SortPriceAsc, SortPriceDesc, SortNameAsc, SortNameDesc:
+		return true
+	}
+	return false
+}
+
+// ProductQuery selects one page of the catalog.
+type ProductQuery struct {
+	Search        string // Full-text search over name and description
+	MinPriceCents *int64
+	MaxPriceCents *int64
+	InStock       bool  // Only products with stock available to buy
+	CategoryID    *uint // Products in this category or any of its descendants
+	Sort          ProductSort
+	Cursor        string // NextCursor of the previous page; empty for the first page
+	Limit         int
+}
+
+// ProductPage is one page of a listing. NextCursor is empty on the last page;
+// Total counts all matching products, not just this page.
+type ProductPage struct {
+	Products   []Product
+	NextCursor string
+	Total      int64
+	// Snippets holds search excerpts by product ID as HTML: the text is
+	// escaped and the matched words are wrapped in <mark>.
+	Snippets map[uint]string
+	// Approximate is set when no product matched the search terms exactly and
+	// the page lists products with similarly spelled names instead.
+	Approximate bool
+}
+
 type ProductRepository interface {
 	Create(product *Product) error
-	FindAll(query string) ([]Product, error)
+	// FindAll returns a page of products; a malformed cursor, or one from a
+	// listing with a different sort, fails with ErrInvalidQuery.
+	FindAll(query ProductQuery) (*ProductPage, error)
 	FindByID(id uint) (*Product, error)
+	// FindByIDs loads the live products among ids, without associations.
+	FindByIDs(ids []uint) ([]Product, error)
+	// Update saves the catalog fields if the stored version still equals
+	// product.Version, and bumps it. It fails with ErrVersionConflict otherwise.
 	Update(product *Product) error
+	// Delete soft-deletes the product; Restore brings a deleted one back.
 	Delete(id uint) error
-}
\ No newline at end of file
+	Restore(id uint) error
+	// SetCategories replaces the product's category assignments.
+	SetCategories(productID uint, categories []Category) error
+}
@@ -0,0 +1,49 @@
+package domain
+
+import (
+	"fmt"
+)
+
+// ProductRow is one variant in a bulk import or export, together with its
+// product's catalog fields. Rows are matched to variants by SKU.
+type ProductRow struct {
+	Line              int // Position in the import file, for error reports
+	SKU               string
+	ProductID         uint // On import, adds a new SKU to this existing product
+	Name              string
+	Description       string
+	PriceCents        int64
+	VariantPriceCents *int64 // Overrides the product price; nil means none
+	Inventory         *int   // Units on hand; nil leaves stock unchanged on import
+	Options           map[string]string
+}
+
+// RowError reports why one row of an import was rejected.
+type RowError struct {
+	Line    int
+	SKU     string
+	Message string
+}
+
+func (e *RowError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+}
+
+// ProductRowReader yields the rows of an import file.
+type ProductRowReader interface {
+	// Next returns the next row, io.EOF after the last one, or a *RowError
+	// for a row that could not be parsed; reading may continue after that.
+	Next() (*ProductRow, error)
+}
+
+// ImportResult summarizes a bulk import. Nothing is saved unless Committed.
+type ImportResult struct {
+	Rows            int
+	CreatedVariants int
+	UpdatedVariants int
+	CreatedProducts int
+	Errors          []RowError
+	ErrorCount      int // Can exceed len(Errors), which is capped
+	DryRun          bool
+	Committed       bool
+}
@@ -0,0 +1,32 @@
+package domain
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RefreshToken is a server-side record of an opaque refresh token. Only the
+// SHA-256 hash of the token is stored. Every token minted by rotating another
+// shares its FamilyID, so a replayed token can revoke the whole login session.
+type RefreshToken struct {
+	gorm.Model
+	UserID       uint      `gorm:"index;not null"`
+	FamilyID     string    `gorm:"index;not null"`
+	TokenHash    string    `gorm:"uniqueIndex;not null"`
+	ExpiresAt    time.Time `gorm:"not null"`
+	RevokedAt    *time.Time
+	ReplacedByID *uint // The token this one was rotated into
+	MFA          bool  `gorm:"not null;default:false"` // The session passed a second factor at login
+}
+
+type RefreshTokenRepository interface {
+	Create(token *RefreshToken) error
+	FindByHash(tokenHash string) (*RefreshToken, error)
+	Update(token *RefreshToken) error
+	// Revoke marks the token revoked and reports false if it already was, which
+	// lets concurrent rotations of the same token detect each other.
+	Revoke(id uint) (bool, error)
+	RevokeFamily(familyID string) error
+	RevokeAllForUser(userID uint) error
+}
@@ -0,0 +1,45 @@
+package domain
+
+import (
+	"gorm.io/gorm"
+)
+
+// Statuses of a refund before the provider's own status is known.
+const (
+	RefundStatusPending = "pending" // Claimed on the order; the provider has not confirmed it yet
+	RefundStatusFailed  = "failed"  // The provider refused it; its lines can be refunded again
+)
+
+// Refund records money given back on an order, in full or for some line items.
+type Refund struct {
+	gorm.Model
+	OrderID          uint   `gorm:"index;not null"`
+	AmountCents      int64  `gorm:"not null"`
+	ProviderRefundID string `gorm:"index"`
+	Status           string
+	Reason           string
+	Restocked        bool `gorm:"default:false"`
+	CreatedByID      uint // Admin who issued the refund
+	Items            []RefundItem
+}
+
+type RefundItem struct {
+	gorm.Model
+	RefundID    uint  `gorm:"index;not null"`
+	OrderItemID uint  `gorm:"not null"`
+	ProductID   uint  `gorm:"not null"`
+	VariantID   uint  `gorm:"index"`
+	Quantity    int   `gorm:"not null"`
+	AmountCents int64 `gorm:"not null"`
+}
+
+// RefundLine asks for quantity units of one order item to be refunded.
+type RefundLine struct {
+	OrderItemID uint
+	Quantity    int
+}
+
+type RefundRepository interface {
+	Create(refund *Refund) error
+	Update(refund *Refund) error
+}
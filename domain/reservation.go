@@ -0,0 +1,38 @@
+package domain
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ReservationStatus string
+
+const (
+	ReservationActive    ReservationStatus = "active"    // Holding stock for a pending order
+	ReservationConverted ReservationStatus = "converted" // Payment succeeded, stock permanently taken
+	ReservationReleased  ReservationStatus = "released"  // Expired or checkout undone, stock given back
+)
+
+// StockReservation holds units of a variant for an order while its payment is pending.
+type StockReservation struct {
+	gorm.Model
+	ProductID uint              `gorm:"index;not null"`
+	VariantID uint              `gorm:"index"`
+	Quantity  int               `gorm:"not null"`
+	CartID    uint              `gorm:"index"`
+	OrderID   uint              `gorm:"index;not null"`
+	Status    ReservationStatus `gorm:"type:varchar(16);index;not null;default:'active'"`
+	ExpiresAt time.Time         `gorm:"index;not null"`
+}
+
+type ReservationRepository interface {
+	// Reserve holds stock for res, failing with ErrInsufficientInv if not enough is available.
+	Reserve(res *StockReservation) error
+	// ConvertForOrder turns the order's active reservations into permanent inventory decrements.
+	ConvertForOrder(orderID uint) error
+	// ReleaseForOrder gives the order's active reservations back to available stock.
+	ReleaseForOrder(orderID uint) error
+	// FindExpiredOrderIDs lists orders that still hold reservations past their expiry.
+	FindExpiredOrderIDs(now time.Time) ([]uint, error)
+}
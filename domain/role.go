@@ -0,0 +1,95 @@
+package domain
+
+import (
+	"sort"
+
+	"gorm.io/gorm"
+)
+
+// Permission is a single action staff can be allowed to perform.
+type Permission string
+
+const (
+	PermissionProductsWrite  Permission = "products:write"
+	PermissionInventoryRead  Permission = "inventory:read"
+	PermissionInventoryWrite Permission = "inventory:write"
+	PermissionOrdersRefund   Permission = "orders:refund"
+	PermissionUsersManage    Permission = "users:manage"
+	PermissionAPIKeysManage  Permission = "api_keys:manage"
+	PermissionAll            Permission = "*" // Grants every permission, including ones added later
+)
+
+// AllPermissions lists every concrete permission, i.e. everything but PermissionAll.
+var AllPermissions = []Permission{
+	PermissionProductsWrite, PermissionInventoryRead, PermissionInventoryWrite,
+	PermissionOrdersRefund, PermissionUsersManage, PermissionAPIKeysManage,
+}
+
+// Built-in role names. They are seeded on startup; their permissions can be
+// changed in the database afterwards.
+const (
+	RoleSuperAdmin     = "super_admin"
+	RoleCatalogManager = "catalog_manager"
+	RoleSupportAgent   = "support_agent"
+	RoleFulfillment    = "fulfillment"
+)
+
+type Role struct {
+	gorm.Model
+	Name        string `gorm:"uniqueIndex;not null"`
+	Description string
+	Permissions []RolePermission `gorm:"constraint:OnDelete:CASCADE"`
+}
+
+type RolePermission struct {
+	ID         uint       `gorm:"primarykey"`
+	RoleID     uint       `gorm:"uniqueIndex:idx_role_permission;not null"`
+	Permission Permission `gorm:"uniqueIndex:idx_role_permission;type:varchar(64);not null"`
+}
+
+// DefaultRoles are created on first startup.
+var DefaultRoles = []Role{
+	{Name: RoleSuperAdmin, Description: "Full access", Permissions: []RolePermission{
+		{Permission: PermissionAll},
+	}},
+	{Name: RoleCatalogManager, Description: "Maintains products and stock", Permissions: []RolePermission{
+		{Permission: PermissionProductsWrite}, {Permission: PermissionInventoryRead}, {Permission: PermissionInventoryWrite},
+	}},
+	{Name: RoleSupportAgent, Description: "Handles customer orders and refunds", Permissions: []RolePermission{
+		{Permission: PermissionOrdersRefund}, {Permission: PermissionInventoryRead},
+	}},
+	{Name: RoleFulfillment, Description: "Receives and counts stock", Permissions: []RolePermission{
+		{Permission: PermissionInventoryRead}, {Permission: PermissionInventoryWrite},
+	}},
+}
+
+// RoleNames lists the user's roles. Roles must be preloaded.
+func (u *User) RoleNames() []string {
+	names := make([]string, 0, len(u.Roles))
+	for _, role := range u.Roles {
+		names = append(names, role.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Permissions is the union of the permissions of the user's roles.
+func (u *User) Permissions() []Permission {
+	seen := map[Permission]bool{}
+	perms := []Permission{}
+	for _, role := range u.Roles {
+		for _, rp := range role.Permissions {
+			if !seen[rp.Permission] {
+				seen[rp.Permission] = true
+				perms = append(perms, rp.Permission)
+			}
+		}
+	}
+	sort.Slice(perms, func(i, j int) bool { return perms[i] < perms[j] })
+	return perms
+}
+
+type RoleRepository interface {
+	FindAll() ([]Role, error)
+	FindByNames(names []string) ([]Role, error)
+}
@@ -0,0 +1,25 @@
+package domain
+
+// Repositories groups the repositories that take part in a single unit of work.
+type Repositories struct {
+	Users        UserRepository
+	Products     ProductRepository
+	Variants     VariantRepository
+	Carts        CartRepository
+	Orders       OrderRepository
+	Events       ProcessedEventRepository
+	Refunds      RefundRepository
+	Reservations ReservationRepository
+	Inventory    InventoryRepository
+
+	RefreshTokens RefreshTokenRepository
+	UserTokens    UserTokenRepository
+	RecoveryCodes RecoveryCodeRepository
+	Identities    IdentityRepository
+}
+
+// UnitOfWork runs fn inside one database transaction. If fn returns an error
+// every change made through the supplied repositories is rolled back.
+type UnitOfWork interface {
+	Do(fn func(repos Repositories) error) error
+}
@@ -1,13 +1,36 @@
 package domain
 
 import (
+	"time"
+
 	"gorm.io/gorm"
 )
 
 type User struct {
 	gorm.Model
-	Username string `gorm:"unique;not null"`
-	Password string `gorm:"not null"` // Hashed password
-	IsAdmin  bool   `gorm:"default:false"`
-	Cart     Cart   `gorm:"foreignKey:UserID"`
-}
\ No newline at end of file
+	Username   string     `gorm:"unique;not null"`
+	Password   string     `gorm:"not null"`    // Hashed password
+	Email      *string    `gorm:"uniqueIndex"` // Lower-cased; nil for accounts created before emails were collected
+	VerifiedAt *time.Time // When Email was confirmed; reset when it changes
+
+	TOTPSecret    string // Base32 secret; set on enrolment, active once TOTPEnabledAt is set
+	TOTPEnabledAt *time.Time
+	TOTPLastStep  int64  // Last accepted time step, so a code cannot be replayed
+	Roles         []Role `gorm:"many2many:user_roles"`
+	Cart          Cart   `gorm:"foreignKey:UserID"`
+}
+
+// MFAEnabled reports whether the user has a confirmed second factor.
+func (u *User) MFAEnabled() bool {
+	return u.TOTPEnabledAt != nil
+}
+
+// IsStaff reports whether the user holds any permission, i.e. is an admin of some kind.
+func (u *User) IsStaff() bool {
+	return len(u.Permissions()) > 0
+}
+
+// EmailVerified reports whether the user has confirmed their current email.
+func (u *User) EmailVerified() bool {
+	return u.Email != nil && u.VerifiedAt != nil
+}
@@ -1,7 +1,20 @@
 package domain
 
+import "time"
+
 type UserRepository interface {
 	Create(user *User) error
 	FindByUsername(username string) (*User, error)
 	FindByID(id uint) (*User, error)
-}
\ No newline at end of file
+	FindByEmail(email string) (*User, error)
+	UpdatePassword(userID uint, hashedPassword string) error
+	// UpdateEmail sets a new, unverified email address.
+	UpdateEmail(userID uint, email string) error
+	MarkEmailVerified(userID uint, at time.Time) error
+	// SetTOTP stores a TOTP secret and enabled time; an empty secret disables TOTP.
+	SetTOTP(userID uint, secret string, enabledAt *time.Time) error
+	// AdvanceTOTPStep records a used time step and reports false if it (or a
+	// later one) was used already.
+	AdvanceTOTPStep(userID uint, step int64) (bool, error)
+	ReplaceRoles(user *User, roles []Role) error
+}
@@ -0,0 +1,36 @@
+package domain
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TokenPurpose says what a single-use user token may be redeemed for.
+type TokenPurpose string
+
+const (
+	TokenPasswordReset     TokenPurpose = "password_reset"
+	TokenEmailVerification TokenPurpose = "email_verification"
+)
+
+// UserToken is a single-use secret sent to a user out of band, e.g. a password
+// reset link. Only the SHA-256 hash of the token is stored.
+type UserToken struct {
+	gorm.Model
+	UserID    uint         `gorm:"index;not null"`
+	Purpose   TokenPurpose `gorm:"type:varchar(32);not null"`
+	TokenHash string       `gorm:"uniqueIndex;not null"`
+	ExpiresAt time.Time    `gorm:"not null"`
+	UsedAt    *time.Time
+}
+
+type UserTokenRepository interface {
+	Create(token *UserToken) error
+	FindByHash(purpose TokenPurpose, tokenHash string) (*UserToken, error)
+	// MarkUsed redeems the token and reports false if it was already used, so
+	// concurrent redemptions of the same token cannot both succeed.
+	MarkUsed(id uint) (bool, error)
+	// InvalidateForUser expires the user's outstanding tokens for purpose.
+	InvalidateForUser(userID uint, purpose TokenPurpose) error
+}
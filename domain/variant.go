@@ -0,0 +1,104 @@
+package domain
+
+import (
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// OptionType is a dimension products come in, such as "size" or "colour".
+type OptionType struct {
+	gorm.Model
+	Name string `gorm:"uniqueIndex;not null"`
+}
+
+// ProductVariant is one sellable version of a product, e.g. the red shirt in
+// size M. Stock is kept per variant; Product.Inventory and Product.Reserved are
+// the totals over its variants. Every product has at least one variant.
+type ProductVariant struct {
+	gorm.Model
+	ProductID  uint            `gorm:"index;not null"`
+	SKU        string          `gorm:"uniqueIndex;not null"`
+	PriceCents *int64          // Overrides the product price when set
+	Inventory  int             `gorm:"not null;default:0"` // Units on hand
+	Reserved   int             `gorm:"not null;default:0"` // Units held for pending checkouts
+	Options    []VariantOption `gorm:"foreignKey:VariantID"`
+}
+
+// VariantOption is the variant's value for one option type, e.g. size = "M".
+type VariantOption struct {
+	ID           uint       `gorm:"primarykey"`
+	VariantID    uint       `gorm:"uniqueIndex:idx_variant_option;not null"`
+	OptionTypeID uint       `gorm:"uniqueIndex:idx_variant_option;not null"`
+	OptionType   OptionType `gorm:"constraint:OnDelete:RESTRICT"`
+	Value        string     `gorm:"not null"`
+}
+
+// VariantChanges lists the variant fields to update; nil fields are left as
+// they are. ClearPrice removes the price override.
+type VariantChanges struct {
+	SKU        *string
+	PriceCents *int64
+	ClearPrice bool
+}
+
+// Available is the stock of the variant that can still be added to carts and checked out.
+func (v *ProductVariant) Available() int {
+	return v.Inventory - v.Reserved
+}
+
+// Price is the variant's unit price given its product's price.
+func (v *ProductVariant) Price(productPrice int64) int64 {
+	if v.PriceCents != nil {
+		return *v.PriceCents
+	}
+	return productPrice
+}
+
+// OptionMap returns the options as option type name to value. Option types
+// must be preloaded.
+func (v *ProductVariant) OptionMap() map[string]string {
+	options := make(map[string]string, len(v.Options))
+	for _, o := range v.Options {
+		options[o.OptionType.Name] = o.Value
+	}
+	return options
+}
+
+// Label describes the options for line items, e.g. "colour: red, size: M".
+func (v *ProductVariant) Label() string {
+	parts := make([]string, 0, len(v.Options))
+	for _, o := range v.Options {
+		parts = append(parts, o.OptionType.Name+": "+o.Value)
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ", ")
+}
+
+type OptionTypeRepository interface {
+	Create(optionType *OptionType) error
+	FindAll() ([]OptionType, error)
+	FindByNames(names []string) ([]OptionType, error)
+}
+
+type VariantRepository interface {
+	// Create stores the variant with its options. Stock is booked separately
+	// through the inventory ledger.
+	Create(variant *ProductVariant) error
+	// FindByID loads the variant with its options; variants of deleted
+	// products are not found.
+	FindByID(id uint) (*ProductVariant, error)
+	FindByProductID(productID uint) ([]ProductVariant, error)
+	FindBySKU(sku string) (*ProductVariant, error)
+	// FindBySKUs loads the variants with any of the SKUs, like FindBySKU.
+	FindBySKUs(skus []string) ([]ProductVariant, error)
+	// FindAfter returns up to limit variants of live products with IDs above
+	// afterID, in ID order, for walking the whole catalog.
+	FindAfter(afterID uint, limit int) ([]ProductVariant, error)
+	// Update saves the SKU and price override.
+	Update(variant *ProductVariant) error
+	// Delete removes a variant with no stock on hand or reserved, failing
+	// with ErrVariantHasStock otherwise.
+	Delete(id uint) error
+}
@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"ecommerce-api/domain"
+)
+
+// --- ADMIN API KEY HANDLERS ---
+
+func (h *APIHandler) CreateAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	claims := GetUserClaims(r)
+
+	var req struct {
+		Name      string              `json:"name"`
+		Scopes    []domain.Permission `json:"scopes"`
+		ExpiresAt *time.Time          `json:"expires_at"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	key, plaintext, err := h.Service.CreateAPIKey(claims, req.Name, req.Scopes, req.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidAPIKeyRequest) {
+			RespondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		RespondError(w, http.StatusInternalServerError, "Could not create API key")
+		return
+	}
+
+	output := apiKeyOutput(key)
+	output["key"] = plaintext // Only ever shown here
+	RespondJSON(w, http.StatusCreated, output)
+}
+
+func (h *APIHandler) ListAPIKeysHandler(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.Service.ListAPIKeys()
+	if err != nil {
+		RespondError(w, http.StatusInternalServerError, "Could not retrieve API keys")
+		return
+	}
+
+	output := make([]map[string]interface{}, 0, len(keys))
+	for i := range keys {
+		output = append(output, apiKeyOutput(&keys[i]))
+	}
+	RespondJSON(w, http.StatusOK, output)
+}
+
+func (h *APIHandler) RevokeAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	keyID, err := PathID(r, "id")
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, "Invalid API key ID")
+		return
+	}
+
+	if err := h.Service.RevokeAPIKey(keyID); err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			RespondError(w, http.StatusNotFound, "API key not found or already revoked")
+			return
+		}
+		RespondError(w, http.StatusInternalServerError, "Could not revoke API key")
+		return
+	}
+	RespondJSON(w, http.StatusOK, map[string]interface{}{"message": "API key revoked"})
+}
+
+func apiKeyOutput(key *domain.APIKey) map[string]interface{} {
+	return map[string]interface{}{
+		"id":           key.ID,
+		"name":         key.Name,
+		"prefix":       key.Prefix,
+		"scopes":       key.Scopes,
+		"created_by":   key.CreatedByID,
+		"created_at":   key.CreatedAt,
+		"expires_at":   key.ExpiresAt,
+		"last_used_at": key.LastUsedAt,
+		"revoked_at":   key.RevokedAt,
+	}
+}
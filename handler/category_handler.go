@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"ecommerce-api/domain"
+)
+
+// --- CATEGORY HANDLERS ---
+
+func (h *APIHandler) ListCategoriesHandler(w http.ResponseWriter, r *http.Request) {
+	tree, err := h.Service.CategoryTree()
+	if err != nil {
+		RespondError(w, http.StatusInternalServerError, "Failed to retrieve categories")
+		return
+	}
+	RespondJSON(w, http.StatusOK, categoryTreeOutput(tree))
+}
+
+type categoryRequest struct {
+	Name     string `json:"name"`
+	Slug     string `json:"slug"`
+	ParentID *uint  `json:"parent_id"`
+}
+
+func (h *APIHandler) CreateCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	var req categoryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	category := &domain.Category{Name: req.Name, Slug: req.Slug, ParentID: req.ParentID}
+	if err := h.Service.CreateCategory(category); err != nil {
+		respondCategoryError(w, err, "Could not create category")
+		return
+	}
+	RespondJSON(w, http.StatusCreated, categoryOutput(category))
+}
+
+// UpdateCategoryHandler replaces name, slug and parent; a null parent_id makes
+// the category a root.
+func (h *APIHandler) UpdateCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	categoryID, err := PathID(r, "id")
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, "Invalid category ID")
+		return
+	}
+
+	var req categoryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	category, err := h.Service.UpdateCategory(categoryID, req.Name, req.Slug, req.ParentID)
+	if err != nil {
+		respondCategoryError(w, err, "Could not update category")
+		return
+	}
+	RespondJSON(w, http.StatusOK, categoryOutput(category))
+}
+
+func (h *APIHandler) DeleteCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	categoryID, err := PathID(r, "id")
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, "Invalid category ID")
+		return
+	}
+
+	if err := h.Service.DeleteCategory(categoryID); err != nil {
+		respondCategoryError(w, err, "Could not delete category")
+		return
+	}
+	RespondJSON(w, http.StatusOK, map[string]interface{}{"message": "Category deleted"})
+}
+
+func (h *APIHandler) SetProductCategoriesHandler(w http.ResponseWriter, r *http.Request) {
+	productID, err := PathID(r, "id")
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, "Invalid product ID")
+		return
+	}
+
+	var req struct {
+		CategoryIDs []uint `json:"category_ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	product, err := h.Service.SetProductCategories(productID, req.CategoryIDs)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			RespondError(w, http.StatusNotFound, "Product not found")
+			return
+		}
+		respondCategoryError(w, err, "Could not update product categories")
+		return
+	}
+	RespondJSON(w, http.StatusOK, h.productDetailOutput(product))
+}
+
+func respondCategoryError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		RespondError(w, http.StatusNotFound, "Category not found")
+	case errors.Is(err, domain.ErrInvalidCategory):
+		RespondError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, domain.ErrCategorySlugTaken), errors.Is(err, domain.ErrCategoryHasChildren):
+		RespondError(w, http.StatusConflict, err.Error())
+	default:
+		RespondError(w, http.StatusInternalServerError, fallback)
+	}
+}
+
+func categoryOutput(c *domain.Category) map[string]interface{} {
+	return map[string]interface{}{
+		"id":        c.ID,
+		"name":      c.Name,
+		"slug":      c.Slug,
+		"parent_id": c.ParentID,
+	}
+}
+
+func categoryTreeOutput(categories []domain.Category) []map[string]interface{} {
+	output := make([]map[string]interface{}, 0, len(categories))
+	for i := range categories {
+		node := categoryOutput(&categories[i])
+		node["children"] = categoryTreeOutput(categories[i].Children)
+		output = append(output, node)
+	}
+	return output
+}
@@ -0,0 +1,24 @@
+package handler
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ClientIP returns the address of the caller. Behind a reverse proxy the last
+// X-Forwarded-For entry is used: it was added by our proxy, whereas earlier
+// entries come from the client and can be forged.
+func (h *APIHandler) ClientIP(r *http.Request) string {
+	if h.TrustProxyHeaders {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			parts := strings.Split(forwarded, ",")
+			return strings.TrimSpace(parts[len(parts)-1])
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
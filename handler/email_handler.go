@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"ecommerce-api/domain"
+)
+
+// --- EMAIL VERIFICATION HANDLERS ---
+
+func (h *APIHandler) RequestEmailVerificationHandler(w http.ResponseWriter, r *http.Request) {
+	claims := GetUserClaims(r)
+	if claims == nil {
+		RespondError(w, http.StatusUnauthorized, "User context missing")
+		return
+	}
+
+	// The body is optional; without one the token goes to the current address
+	var req struct {
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		RespondError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	if err := h.Service.RequestEmailVerification(claims.UserID, req.Email); err != nil {
+		switch {
+		case errors.Is(err, domain.ErrInvalidEmail), errors.Is(err, domain.ErrEmailRequired):
+			RespondError(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, domain.ErrEmailTaken), errors.Is(err, domain.ErrEmailAlreadyVerified):
+			RespondError(w, http.StatusConflict, err.Error())
+		default:
+			RespondError(w, http.StatusInternalServerError, "Could not send verification")
+		}
+		return
+	}
+	RespondJSON(w, http.StatusAccepted, map[string]interface{}{"message": "Verification email sent"})
+}
+
+// ConfirmEmailHandler is a GET so the token can be delivered as a plain link.
+func (h *APIHandler) ConfirmEmailHandler(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		RespondError(w, http.StatusBadRequest, "token is required")
+		return
+	}
+
+	if err := h.Service.ConfirmEmail(token); err != nil {
+		if errors.Is(err, domain.ErrInvalidToken) {
+			RespondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		RespondError(w, http.StatusInternalServerError, "Could not verify email")
+		return
+	}
+	RespondJSON(w, http.StatusOK, map[string]interface{}{"message": "Email verified"})
+}
@@ -5,6 +5,8 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"ecommerce-api/domain"
 	"ecommerce-api/service"
@@ -14,6 +16,12 @@ import (
 type APIHandler struct {
 	Service    service.ECommerceService
 	JWTService service.JWTService
+	Webhooks   service.WebhookVerifier
+
+	// TrustProxyHeaders takes the client IP from X-Forwarded-For.
+	TrustProxyHeaders bool
+	// MaxImageBytes caps product image uploads.
+	MaxImageBytes int64
 }
 
 // Utility function to respond with JSON
@@ -43,52 +51,292 @@ func GetUserClaims(r *http.Request) *domain.Claims {
 	return claims
 }
 
+// PathID parses a numeric path parameter such as {id} from the request
+func PathID(r *http.Request, name string) (uint, error) {
+	id, err := strconv.ParseUint(r.PathValue(name), 10, 0)
+	if err != nil || id == 0 {
+		return 0, errors.New("invalid " + name)
+	}
+	return uint(id), nil
+}
+
 // --- USER AND AUTH HANDLERS ---
 
 // --- ADMIN PRODUCT HANDLERS ---
 
+// CreateProductHandler creates a product. Without variants it gets a single
+// default variant holding Inventory.
 func (h *APIHandler) CreateProductHandler(w http.ResponseWriter, r *http.Request) {
-	var product domain.Product
-	if err := json.NewDecoder(r.Body).Decode(&product); err != nil {
+	var req struct {
+		domain.Product
+		Variants []variantRequest `json:"variants"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		RespondError(w, http.StatusBadRequest, "Invalid request payload")
 		return
 	}
-
-	// Business validation in service, but initial checks here
-	if product.Name == "" || product.PriceCents <= 0 || product.Inventory < 0 {
-		RespondError(w, http.StatusBadRequest, "Product name, price, and inventory must be valid")
-		return
+	product := req.Product
+	for i := range req.Variants {
+		product.Variants = append(product.Variants, req.Variants[i].variant())
 	}
 
 	if err := h.Service.CreateProduct(&product); err != nil {
-		RespondError(w, http.StatusInternalServerError, "Could not create product")
+		switch {
+		case errors.Is(err, domain.ErrInvalidProduct), errors.Is(err, domain.ErrInvalidVariant):
+			RespondError(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, domain.ErrSKUTaken):
+			RespondError(w, http.StatusConflict, err.Error())
+		default:
+			RespondError(w, http.StatusInternalServerError, "Could not create product")
+		}
 		return
 	}
 	RespondJSON(w, http.StatusCreated, product)
 }
 
+// UpdateProductHandler serves PUT, which replaces the catalog fields, and PATCH,
+// which only changes the fields present. The version the edit is based on comes
+// from an If-Match header or the "version" field.
+func (h *APIHandler) UpdateProductHandler(w http.ResponseWriter, r *http.Request) {
+	productID, err := PathID(r, "id")
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, "Invalid product ID")
+		return
+	}
+
+	var req struct {
+		Name        *string `json:"name"`
+		Description *string `json:"description"`
+		PriceCents  *int64  `json:"price_cents"`
+		Inventory   *int    `json:"inventory"`
+		Version     *int    `json:"version"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	if req.Inventory != nil {
+		RespondError(w, http.StatusBadRequest, "Inventory cannot be updated here; use the inventory adjustment endpoint")
+		return
+	}
+	if r.Method == http.MethodPut {
+		if req.Name == nil || req.PriceCents == nil {
+			RespondError(w, http.StatusBadRequest, "PUT requires name and price_cents; use PATCH for partial updates")
+			return
+		}
+		if req.Description == nil {
+			req.Description = new(string)
+		}
+	}
+
+	version, ok := expectedVersion(r, req.Version)
+	if !ok {
+		RespondError(w, http.StatusPreconditionRequired, "Send the product version in an If-Match header or the version field")
+		return
+	}
+
+	product, err := h.Service.UpdateProduct(productID, version, domain.ProductChanges{
+		Name:        req.Name,
+		Description: req.Description,
+		PriceCents:  req.PriceCents,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrNotFound):
+			RespondError(w, http.StatusNotFound, "Product not found")
+		case errors.Is(err, domain.ErrVersionConflict):
+			RespondError(w, http.StatusConflict, err.Error())
+		case errors.Is(err, domain.ErrInvalidProduct):
+			RespondError(w, http.StatusBadRequest, err.Error())
+		default:
+			RespondError(w, http.StatusInternalServerError, "Could not update product")
+		}
+		return
+	}
+	w.Header().Set("ETag", productETag(product))
+	RespondJSON(w, http.StatusOK, h.productDetailOutput(product))
+}
+
+func (h *APIHandler) DeleteProductHandler(w http.ResponseWriter, r *http.Request) {
+	productID, err := PathID(r, "id")
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, "Invalid product ID")
+		return
+	}
+
+	if err := h.Service.DeleteProduct(productID); err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			RespondError(w, http.StatusNotFound, "Product not found")
+			return
+		}
+		RespondError(w, http.StatusInternalServerError, "Could not delete product")
+		return
+	}
+	RespondJSON(w, http.StatusOK, map[string]interface{}{"message": "Product deleted"})
+}
+
+func (h *APIHandler) RestoreProductHandler(w http.ResponseWriter, r *http.Request) {
+	productID, err := PathID(r, "id")
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, "Invalid product ID")
+		return
+	}
+
+	product, err := h.Service.RestoreProduct(productID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			RespondError(w, http.StatusNotFound, "Deleted product not found")
+			return
+		}
+		RespondError(w, http.StatusInternalServerError, "Could not restore product")
+		return
+	}
+	w.Header().Set("ETag", productETag(product))
+	RespondJSON(w, http.StatusOK, h.productDetailOutput(product))
+}
+
+// expectedVersion reads the version an edit is based on, preferring If-Match.
+func expectedVersion(r *http.Request, bodyVersion *int) (int, bool) {
+	if match := r.Header.Get("If-Match"); match != "" {
+		version, err := strconv.Atoi(strings.Trim(strings.TrimPrefix(match, "W/"), `"`))
+		return version, err == nil
+	}
+	if bodyVersion != nil {
+		return *bodyVersion, true
+	}
+	return 0, false
+}
+
+func productETag(p *domain.Product) string {
+	return strconv.Quote(strconv.Itoa(p.Version))
+}
+
 // --- PUBLIC PRODUCT HANDLERS ---
 
 func (h *APIHandler) GetProductsHandler(w http.ResponseWriter, r *http.Request) {
-	query := r.URL.Query().Get("q")
-	products, err := h.Service.GetProducts(query)
+	params := r.URL.Query()
+	query := domain.ProductQuery{
+		Search: params.Get("q"),
+		Sort:   domain.ProductSort(params.Get("sort")),
+		Cursor: params.Get("cursor"),
+	}
+	var err error
+	if query.MinPriceCents, err = optionalInt64Param(params.Get("min_price_cents")); err != nil {
+		RespondError(w, http.StatusBadRequest, "Invalid min_price_cents")
+		return
+	}
+	if query.MaxPriceCents, err = optionalInt64Param(params.Get("max_price_cents")); err != nil {
+		RespondError(w, http.StatusBadRequest, "Invalid max_price_cents")
+		return
+	}
+	if v := params.Get("in_stock"); v != "" {
+		if query.InStock, err = strconv.ParseBool(v); err != nil {
+			RespondError(w, http.StatusBadRequest, "Invalid in_stock")
+			return
+		}
+	}
+	if v := params.Get("category_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 0)
+		if err != nil || id == 0 {
+			RespondError(w, http.StatusBadRequest, "Invalid category_id")
+			return
+		}
+		categoryID := uint(id)
+		query.CategoryID = &categoryID
+	}
+	if v := params.Get("limit"); v != "" {
+		if query.Limit, err = strconv.Atoi(v); err != nil || query.Limit <= 0 {
+			RespondError(w, http.StatusBadRequest, "Invalid limit")
+			return
+		}
+	}
+
+	page, err := h.Service.GetProducts(query)
 	if err != nil {
+		if errors.Is(err, domain.ErrInvalidQuery) {
+			RespondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		RespondError(w, http.StatusInternalServerError, "Failed to retrieve products")
 		return
 	}
 
-	// Format price for display (Price is stored in cents, display in dollars)
-	displayProducts := make([]map[string]interface{}, len(products))
-	for i, p := range products {
-		displayProducts[i] = map[string]interface{}{
-			"id":          p.ID,
-			"name":        p.Name,
-			"description": p.Description,
-			"price":       fmt.Sprintf("%.2f", float64(p.PriceCents)/100.0), // Convert cents to dollars
-			"inventory":   p.Inventory,
+	displayProducts := make([]map[string]interface{}, len(page.Products))
+	for i := range page.Products {
+		displayProducts[i] = h.productOutput(&page.Products[i])
+		if snippet, ok := page.Snippets[page.Products[i].ID]; ok {
+			displayProducts[i]["snippet"] = snippet
 		}
 	}
-	RespondJSON(w, http.StatusOK, displayProducts)
+	RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"products":    displayProducts,
+		"next_cursor": page.NextCursor,
+		"total":       page.Total,
+		"approximate": page.Approximate,
+	})
+}
+
+func optionalInt64Param(v string) (*int64, error) {
+	if v == "" {
+		return nil, nil
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n < 0 {
+		return nil, errors.New("invalid number")
+	}
+	return &n, nil
+}
+
+func (h *APIHandler) GetProductHandler(w http.ResponseWriter, r *http.Request) {
+	productID, err := PathID(r, "id")
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, "Invalid product ID")
+		return
+	}
+
+	product, err := h.Service.GetProduct(productID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			RespondError(w, http.StatusNotFound, "Product not found")
+			return
+		}
+		RespondError(w, http.StatusInternalServerError, "Failed to retrieve product")
+		return
+	}
+	w.Header().Set("ETag", productETag(product))
+	RespondJSON(w, http.StatusOK, h.productDetailOutput(product))
+}
+
+// Format price for display (Price is stored in cents, display in dollars)
+func (h *APIHandler) productOutput(p *domain.Product) map[string]interface{} {
+	return map[string]interface{}{
+		"id":          p.ID,
+		"name":        p.Name,
+		"description": p.Description,
+		"price":       fmt.Sprintf("%.2f", float64(p.PriceCents)/100.0), // Convert cents to dollars
+		"price_cents": p.PriceCents,
+		"inventory":   p.Inventory,
+		"reserved":    p.Reserved,
+		"available":   p.Available(),
+		"version":     p.Version,
+		"images":      h.imagesOutput(p.Images),
+	}
+}
+
+// productDetailOutput adds the product's categories and variants, which must be preloaded.
+func (h *APIHandler) productDetailOutput(p *domain.Product) map[string]interface{} {
+	output := h.productOutput(p)
+	categories := make([]map[string]interface{}, 0, len(p.Categories))
+	for i := range p.Categories {
+		categories = append(categories, categoryOutput(&p.Categories[i]))
+	}
+	output["categories"] = categories
+	variants := make([]map[string]interface{}, 0, len(p.Variants))
+	for i := range p.Variants {
+		variants = append(variants, variantOutput(&p.Variants[i], p.PriceCents))
+	}
+	output["variants"] = variants
+	return output
 }
 
 // --- CART HANDLERS (Authenticated) ---
@@ -102,6 +350,7 @@ func (h *APIHandler) AddToCartHandler(w http.ResponseWriter, r *http.Request) {
 
 	var req struct {
 		ProductID uint `json:"product_id"`
+		VariantID uint `json:"variant_id"` // May be omitted for products with a single variant
 		Quantity  int  `json:"quantity"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Quantity <= 0 {
@@ -109,9 +358,9 @@ func (h *APIHandler) AddToCartHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	cart, err := h.Service.AddToCart(claims.UserID, req.ProductID, req.Quantity)
+	cart, err := h.Service.AddToCart(claims.UserID, req.ProductID, req.VariantID, req.Quantity)
 	if err != nil {
-		if errors.Is(err, domain.ErrInsufficientInv) || errors.Is(err, domain.ErrNotFound) {
+		if errors.Is(err, domain.ErrInsufficientInv) || errors.Is(err, domain.ErrNotFound) || errors.Is(err, domain.ErrVariantRequired) {
 			RespondError(w, http.StatusBadRequest, err.Error())
 			return
 		}
@@ -156,12 +405,28 @@ func (h *APIHandler) CheckoutHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := h.Service.Checkout(claims.UserID)
+	result, err := h.Service.Checkout(claims.UserID, GetIdempotencyKey(r))
 	if err != nil {
 		if errors.Is(err, domain.ErrCartEmpty) || errors.Is(err, domain.ErrInsufficientInv) {
 			RespondError(w, http.StatusBadRequest, err.Error())
 			return
 		}
+		if errors.Is(err, domain.ErrEmailNotVerified) {
+			RespondError(w, http.StatusForbidden, err.Error())
+			return
+		}
+		if errors.Is(err, domain.ErrPaymentDeclined) {
+			RespondError(w, http.StatusPaymentRequired, err.Error())
+			return
+		}
+		if errors.Is(err, domain.ErrPaymentTimeout) {
+			RespondError(w, http.StatusGatewayTimeout, err.Error())
+			return
+		}
+		if errors.Is(err, domain.ErrCheckoutFailed) {
+			RespondError(w, http.StatusConflict, err.Error())
+			return
+		}
 		// Generic internal server error for payment gateway issues
 		RespondError(w, http.StatusInternalServerError, "Checkout failed due to internal error.")
 		return
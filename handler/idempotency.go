@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"ecommerce-api/domain"
+)
+
+const (
+	IdempotencyHeader = "Idempotency-Key"
+
+	maxIdempotencyKeyLength = 255
+	maxIdempotentBodyBytes  = 1 << 20
+
+	// idempotencyInFlightTimeout is far longer than any request should run; a
+	// key still in flight after it belongs to a request whose process died, and
+	// a retry takes it over.
+	idempotencyInFlightTimeout = 5 * time.Minute
+)
+
+type idempotencyContextKey struct{}
+
+// GetIdempotencyKey returns the Idempotency-Key accepted by IdempotencyMiddleware, if any.
+func GetIdempotencyKey(r *http.Request) string {
+	key, _ := r.Context().Value(idempotencyContextKey{}).(string)
+	return key
+}
+
+// IdempotencyMiddleware makes POST requests carrying an Idempotency-Key header
+// safe to retry. The first request runs normally and its response is stored;
+// repeats with the same key and body get the stored response replayed, and a
+// key reused with a different body is rejected. Server errors and panics are
+// not stored, so the client can retry them with the same key. Wrap it inside
+// AuthMiddleware so keys are scoped to the authenticated user.
+func IdempotencyMiddleware(store domain.IdempotencyRepository, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyHeader)
+		if r.Method != http.MethodPost || key == "" {
+			next(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			RespondError(w, http.StatusBadRequest, "Idempotency-Key is too long")
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIdempotentBodyBytes))
+		if err != nil {
+			RespondError(w, http.StatusBadRequest, "Could not read request body")
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		record := &domain.IdempotencyRecord{
+			Key:         key,
+			Method:      r.Method,
+			Path:        r.URL.Path,
+			RequestHash: requestFingerprint(r, body),
+		}
+		if claims := GetUserClaims(r); claims != nil {
+			record.UserID = claims.UserID
+		}
+
+		existing, err := store.Reserve(record, time.Now().Add(-idempotencyInFlightTimeout))
+		if errors.Is(err, domain.ErrNotFound) {
+			RespondError(w, http.StatusConflict, "A request with this Idempotency-Key is still being processed")
+			return
+		}
+		if err != nil {
+			log.Printf("Idempotency store failure for key %q: %v", key, err)
+			RespondError(w, http.StatusInternalServerError, "Could not process Idempotency-Key")
+			return
+		}
+		if existing != nil {
+			if existing.RequestHash != record.RequestHash {
+				RespondError(w, http.StatusUnprocessableEntity, "Idempotency-Key was already used with a different request")
+				return
+			}
+			if existing.CompletedAt == nil {
+				RespondError(w, http.StatusConflict, "A request with this Idempotency-Key is still being processed")
+				return
+			}
+			if existing.ContentType != "" {
+				w.Header().Set("Content-Type", existing.ContentType)
+			}
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(existing.StatusCode)
+			w.Write(existing.ResponseBody)
+			return
+		}
+
+		release := func() {
+			if err := store.Release(record); err != nil {
+				log.Printf("Failed to release Idempotency-Key %q: %v", key, err)
+			}
+		}
+		defer func() {
+			// net/http recovers the panic, but the key would stay in flight
+			if p := recover(); p != nil {
+				release()
+				panic(p)
+			}
+		}()
+
+		rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		ctx := context.WithValue(r.Context(), idempotencyContextKey{}, key)
+		next(rec, r.WithContext(ctx))
+
+		if rec.status >= http.StatusInternalServerError {
+			release()
+			return
+		}
+
+		now := time.Now()
+		record.StatusCode = rec.status
+		record.ContentType = rec.Header().Get("Content-Type")
+		record.ResponseBody = rec.body.Bytes()
+		record.CompletedAt = &now
+		if err := store.Complete(record); err != nil {
+			log.Printf("Failed to store response for Idempotency-Key %q: %v", key, err)
+		}
+	}
+}
+
+func requestFingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method + "\n" + r.URL.Path + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// responseRecorder passes the response through while keeping a copy of it.
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	if !rec.wroteHeader {
+		rec.status = status
+		rec.wroteHeader = true
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	rec.wroteHeader = true
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
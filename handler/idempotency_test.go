@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"ecommerce-api/domain"
+)
+
+// memoryIdempotencyStore keeps records by key, for a single user.
+type memoryIdempotencyStore struct {
+	records map[string]*domain.IdempotencyRecord
+}
+
+func (m *memoryIdempotencyStore) Reserve(record *domain.IdempotencyRecord, staleBefore time.Time) (*domain.IdempotencyRecord, error) {
+	if existing, ok := m.records[record.Key]; ok {
+		if existing.CompletedAt != nil || !existing.CreatedAt.Before(staleBefore) {
+			return existing, nil
+		}
+	}
+	record.CreatedAt = time.Now()
+	stored := *record
+	m.records[record.Key] = &stored
+	return nil, nil
+}
+
+func (m *memoryIdempotencyStore) Complete(record *domain.IdempotencyRecord) error {
+	stored := *record
+	m.records[record.Key] = &stored
+	return nil
+}
+
+func (m *memoryIdempotencyStore) Release(record *domain.IdempotencyRecord) error {
+	delete(m.records, record.Key)
+	return nil
+}
+
+func (m *memoryIdempotencyStore) DeleteCreatedBefore(before time.Time) (int64, error) {
+	return 0, nil
+}
+
+func postWithKey(t *testing.T, h http.HandlerFunc, key string) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodPost, "/api/checkout", strings.NewReader(`{}`))
+	r.Header.Set(IdempotencyHeader, key)
+	w := httptest.NewRecorder()
+	h(w, r)
+	return w
+}
+
+func TestIdempotencyMiddlewareReleasesKeyOnPanic(t *testing.T) {
+	store := &memoryIdempotencyStore{records: map[string]*domain.IdempotencyRecord{}}
+	panicking := IdempotencyMiddleware(store, func(w http.ResponseWriter, r *http.Request) {
+		panic("handler bug")
+	})
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("the panic was swallowed")
+			}
+		}()
+		postWithKey(t, panicking, "k1")
+	}()
+	if _, ok := store.records["k1"]; ok {
+		t.Fatal("the key stayed in flight after the panic")
+	}
+
+	ok := IdempotencyMiddleware(store, func(w http.ResponseWriter, r *http.Request) {
+		RespondJSON(w, http.StatusCreated, map[string]string{"status": "done"})
+	})
+	if w := postWithKey(t, ok, "k1"); w.Code != http.StatusCreated {
+		t.Errorf("retry after the panic: got %d, want 201", w.Code)
+	}
+}
+
+func TestIdempotencyMiddlewareTakesOverStaleKey(t *testing.T) {
+	store := &memoryIdempotencyStore{records: map[string]*domain.IdempotencyRecord{}}
+	h := IdempotencyMiddleware(store, func(w http.ResponseWriter, r *http.Request) {
+		RespondJSON(w, http.StatusCreated, map[string]string{"status": "done"})
+	})
+
+	// A request holding the key is still running
+	running := httptest.NewRequest(http.MethodPost, "/api/checkout", nil)
+	store.records["k1"] = &domain.IdempotencyRecord{Key: "k1", RequestHash: requestFingerprint(running, []byte(`{}`))}
+	store.records["k1"].CreatedAt = time.Now()
+	if w := postWithKey(t, h, "k1"); w.Code != http.StatusConflict {
+		t.Errorf("key in flight: got %d, want 409", w.Code)
+	}
+
+	// Its process died long ago
+	store.records["k1"].CreatedAt = time.Now().Add(-idempotencyInFlightTimeout - time.Second)
+	if w := postWithKey(t, h, "k1"); w.Code != http.StatusCreated {
+		t.Errorf("stale key: got %d, want 201", w.Code)
+	}
+	if w := postWithKey(t, h, "k1"); w.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("the retry's response was not stored: got %d", w.Code)
+	}
+}
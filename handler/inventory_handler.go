@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"ecommerce-api/domain"
+)
+
+const (
+	defaultHistoryLimit = 50
+	maxHistoryLimit     = 500
+)
+
+// --- ADMIN INVENTORY HANDLERS ---
+
+func (h *APIHandler) AdjustInventoryHandler(w http.ResponseWriter, r *http.Request) {
+	claims := GetUserClaims(r)
+	if claims == nil {
+		RespondError(w, http.StatusUnauthorized, "User context missing")
+		return
+	}
+
+	productID, err := PathID(r, "id")
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, "Invalid product ID")
+		return
+	}
+
+	var req struct {
+		VariantID uint   `json:"variant_id"` // May be omitted for products with a single variant
+		Quantity  int    `json:"quantity"`   // Signed change to units on hand
+		Reason    string `json:"reason"`
+		Note      string `json:"note"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	movement, err := h.Service.AdjustInventory(claims.UserID, productID, req.VariantID, req.Quantity, domain.MovementReason(req.Reason), req.Note)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrNotFound):
+			RespondError(w, http.StatusNotFound, "Product or variant not found")
+		case errors.Is(err, domain.ErrInvalidAdjustment), errors.Is(err, domain.ErrVariantRequired):
+			RespondError(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, domain.ErrInsufficientInv):
+			RespondError(w, http.StatusConflict, "Adjustment would leave less stock than is reserved")
+		default:
+			RespondError(w, http.StatusInternalServerError, "Failed to adjust inventory")
+		}
+		return
+	}
+	RespondJSON(w, http.StatusCreated, movement)
+}
+
+func (h *APIHandler) InventoryHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	productID, err := PathID(r, "id")
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, "Invalid product ID")
+		return
+	}
+
+	limit := defaultHistoryLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		limit, err = strconv.Atoi(raw)
+		if err != nil || limit <= 0 || limit > maxHistoryLimit {
+			RespondError(w, http.StatusBadRequest, "limit must be between 1 and 500")
+			return
+		}
+	}
+
+	movements, err := h.Service.GetInventoryHistory(productID, limit)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			RespondError(w, http.StatusNotFound, "Product not found")
+			return
+		}
+		RespondError(w, http.StatusInternalServerError, "Failed to retrieve inventory history")
+		return
+	}
+	RespondJSON(w, http.StatusOK, movements)
+}
@@ -0,0 +1,11 @@
+package handler
+
+import "net/http"
+
+// JWKSHandler publishes the public token verification keys. Downstream
+// services fetch this instead of sharing a signing secret.
+func (h *APIHandler) JWKSHandler(w http.ResponseWriter, r *http.Request) {
+	// Short enough that a rotated-in key is picked up before it signs much
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	RespondJSON(w, http.StatusOK, h.JWTService.PublicKeys())
+}
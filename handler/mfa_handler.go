@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"ecommerce-api/domain"
+)
+
+// --- TWO-FACTOR AUTHENTICATION HANDLERS ---
+
+func (h *APIHandler) EnrollTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	claims := GetUserClaims(r)
+	if claims == nil {
+		RespondError(w, http.StatusUnauthorized, "User context missing")
+		return
+	}
+
+	secret, uri, err := h.Service.EnrollTOTP(claims.UserID)
+	if err != nil {
+		if errors.Is(err, domain.ErrMFAAlreadyEnabled) {
+			RespondError(w, http.StatusConflict, err.Error())
+			return
+		}
+		RespondError(w, http.StatusInternalServerError, "Could not start enrolment")
+		return
+	}
+	RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"secret":      secret,
+		"otpauth_uri": uri,
+	})
+}
+
+func (h *APIHandler) ConfirmTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	claims := GetUserClaims(r)
+	if claims == nil {
+		RespondError(w, http.StatusUnauthorized, "User context missing")
+		return
+	}
+	code, ok := decodeMFACode(w, r)
+	if !ok {
+		return
+	}
+
+	recoveryCodes, err := h.Service.ConfirmTOTP(claims.UserID, code)
+	if err != nil {
+		respondMFAError(w, err)
+		return
+	}
+	RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"message":        "Two-factor authentication enabled; other sessions were signed out",
+		"recovery_codes": recoveryCodes,
+	})
+}
+
+func (h *APIHandler) DisableTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	claims := GetUserClaims(r)
+	if claims == nil {
+		RespondError(w, http.StatusUnauthorized, "User context missing")
+		return
+	}
+	var req struct {
+		Password string `json:"password"`
+		Code     string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Password == "" || req.Code == "" {
+		RespondError(w, http.StatusBadRequest, "password and code are required")
+		return
+	}
+
+	if err := h.Service.DisableTOTP(claims.UserID, req.Password, req.Code); err != nil {
+		if respondThrottled(w, err) {
+			return
+		}
+		respondMFAError(w, err)
+		return
+	}
+	RespondJSON(w, http.StatusOK, map[string]interface{}{"message": "Two-factor authentication disabled"})
+}
+
+func decodeMFACode(w http.ResponseWriter, r *http.Request) (string, bool) {
+	var req struct {
+		Code string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+		RespondError(w, http.StatusBadRequest, "code is required")
+		return "", false
+	}
+	return req.Code, true
+}
+
+func respondMFAError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, domain.ErrInvalidMFACode), errors.Is(err, domain.ErrInvalidCredentials):
+		RespondError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, domain.ErrMFAAlreadyEnabled), errors.Is(err, domain.ErrMFANotEnrolled):
+		RespondError(w, http.StatusConflict, err.Error())
+	default:
+		RespondError(w, http.StatusInternalServerError, "Could not update two-factor authentication")
+	}
+}
@@ -5,37 +5,67 @@ import (
 	"net/http"
 	"strings"
 
+	"ecommerce-api/domain"
 	"ecommerce-api/service"
 )
 
-// AuthMiddleware wraps a JWT service middleware for use in handlers
-func AuthMiddleware(jwtService service.JWTService, next http.HandlerFunc, requiredAdmin bool) http.HandlerFunc {
+// APIKeyAuthenticator resolves an X-API-Key header into claims.
+type APIKeyAuthenticator interface {
+	AuthenticateAPIKey(plaintext string) (*domain.Claims, error)
+}
+
+// Authenticator holds what AuthMiddleware needs to check either kind of credential.
+type Authenticator struct {
+	JWT     service.JWTService
+	APIKeys APIKeyAuthenticator
+}
+
+// AuthMiddleware authenticates the request with a Bearer token or an X-API-Key
+// header and checks the required permission. An empty required permission
+// admits any authenticated user; API keys are only accepted where a permission
+// is required, since they do not stand for a customer.
+func AuthMiddleware(auth Authenticator, next http.HandlerFunc, required domain.Permission) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" {
-			RespondError(w, http.StatusUnauthorized, "Authorization header required")
-			return
-		}
+		var claims *domain.Claims
+		if apiKey := r.Header.Get("X-API-Key"); apiKey != "" {
+			if required == "" {
+				RespondError(w, http.StatusForbidden, "API keys cannot be used for this endpoint")
+				return
+			}
+			var err error
+			claims, err = auth.APIKeys.AuthenticateAPIKey(apiKey)
+			if err != nil {
+				RespondError(w, http.StatusUnauthorized, domain.ErrInvalidAPIKey.Error())
+				return
+			}
+		} else {
+			authHeader := r.Header.Get("Authorization")
+			if authHeader == "" {
+				RespondError(w, http.StatusUnauthorized, "Authorization header required")
+				return
+			}
 
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			RespondError(w, http.StatusUnauthorized, "Invalid token format")
-			return
-		}
+			parts := strings.Split(authHeader, " ")
+			if len(parts) != 2 || parts[0] != "Bearer" {
+				RespondError(w, http.StatusUnauthorized, "Invalid token format")
+				return
+			}
 
-		tokenString := parts[1]
-		claims, err := jwtService.ValidateToken(tokenString)
-		if err != nil {
-			RespondError(w, http.StatusUnauthorized, "Invalid or expired token: "+err.Error())
-			return
+			tokenString := parts[1]
+			var err error
+			claims, err = auth.JWT.ValidateToken(tokenString)
+			if err != nil {
+				RespondError(w, http.StatusUnauthorized, "Invalid or expired token: "+err.Error())
+				return
+			}
 		}
 
-		if requiredAdmin && !claims.IsAdmin {
-			RespondError(w, http.StatusForbidden, "Access denied: Admin privilege required")
+		if required != "" && !claims.HasPermission(required) {
+			RespondError(w, http.StatusForbidden, "Access denied: "+string(required)+" permission required")
 			return
 		}
 
-		// Attach the user ID and Admin status to the request context
+		// Attach the user ID, roles and permissions to the request context
 		ctx := context.WithValue(r.Context(), service.UserContextKey, claims)
 		next(w, r.WithContext(ctx))
 	}
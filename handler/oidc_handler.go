@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+
+	"ecommerce-api/domain"
+)
+
+// The state cookie ties the callback to the browser that started the login,
+// so an attacker cannot log a victim into the attacker's account.
+const (
+	oidcStateCookie = "oidc_state"
+	oidcCookiePath  = "/api/auth/oidc/"
+)
+
+// --- EXTERNAL LOGIN (OIDC) HANDLERS (Public) ---
+
+func (h *APIHandler) OIDCStartHandler(w http.ResponseWriter, r *http.Request) {
+	authURL, state, err := h.Service.StartOIDCLogin(r.PathValue("provider"))
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrUnknownProvider):
+			RespondError(w, http.StatusNotFound, err.Error())
+		case errors.Is(err, domain.ErrExternalLogin):
+			RespondError(w, http.StatusBadGateway, "Identity provider is unavailable")
+		default:
+			RespondError(w, http.StatusInternalServerError, "Could not start login")
+		}
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     oidcCookiePath,
+		MaxAge:   600,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode, // Must survive the top-level redirect back from the provider
+	})
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+func (h *APIHandler) OIDCCallbackHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if providerErr := query.Get("error"); providerErr != "" {
+		RespondError(w, http.StatusUnauthorized, "Login was not completed: "+providerErr)
+		return
+	}
+	state, code := query.Get("state"), query.Get("code")
+	if state == "" || code == "" {
+		RespondError(w, http.StatusBadRequest, "state and code are required")
+		return
+	}
+
+	cookie, err := r.Cookie(oidcStateCookie)
+	if err != nil || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(state)) != 1 {
+		RespondError(w, http.StatusBadRequest, "Login state does not match this browser")
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: oidcStateCookie, Value: "", Path: oidcCookiePath, MaxAge: -1})
+
+	user, err := h.Service.FinishOIDCLogin(r.PathValue("provider"), state, code)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrUnknownProvider):
+			RespondError(w, http.StatusNotFound, err.Error())
+		case errors.Is(err, domain.ErrInvalidToken), errors.Is(err, domain.ErrExternalLogin):
+			RespondError(w, http.StatusUnauthorized, err.Error())
+		default:
+			RespondError(w, http.StatusInternalServerError, "Could not complete login")
+		}
+		return
+	}
+
+	h.completeLogin(w, user)
+}
@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"ecommerce-api/domain"
+)
+
+// --- ORDER HANDLERS (Authenticated) ---
+
+func (h *APIHandler) GetOrdersHandler(w http.ResponseWriter, r *http.Request) {
+	claims := GetUserClaims(r)
+	if claims == nil {
+		RespondError(w, http.StatusUnauthorized, "User context missing")
+		return
+	}
+
+	orders, err := h.Service.GetOrders(claims.UserID)
+	if err != nil {
+		RespondError(w, http.StatusInternalServerError, "Failed to retrieve orders")
+		return
+	}
+	RespondJSON(w, http.StatusOK, orders)
+}
+
+func (h *APIHandler) GetOrderHandler(w http.ResponseWriter, r *http.Request) {
+	claims := GetUserClaims(r)
+	if claims == nil {
+		RespondError(w, http.StatusUnauthorized, "User context missing")
+		return
+	}
+
+	orderID, err := PathID(r, "id")
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, "Invalid order ID")
+		return
+	}
+
+	order, err := h.Service.GetOrder(claims.UserID, orderID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			RespondError(w, http.StatusNotFound, "Order not found")
+			return
+		}
+		RespondError(w, http.StatusInternalServerError, "Failed to retrieve order")
+		return
+	}
+	RespondJSON(w, http.StatusOK, order)
+}
+
+// --- ADMIN ORDER HANDLERS ---
+
+func (h *APIHandler) RefundOrderHandler(w http.ResponseWriter, r *http.Request) {
+	claims := GetUserClaims(r)
+	if claims == nil {
+		RespondError(w, http.StatusUnauthorized, "User context missing")
+		return
+	}
+
+	orderID, err := PathID(r, "id")
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, "Invalid order ID")
+		return
+	}
+
+	var req struct {
+		Items []struct {
+			OrderItemID uint `json:"order_item_id"`
+			Quantity    int  `json:"quantity"`
+		} `json:"items"` // Empty means refund everything still refundable
+		Restock bool   `json:"restock"`
+		Reason  string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	lines := make([]domain.RefundLine, len(req.Items))
+	for i, item := range req.Items {
+		lines[i] = domain.RefundLine{OrderItemID: item.OrderItemID, Quantity: item.Quantity}
+	}
+
+	refund, err := h.Service.RefundOrder(claims.UserID, orderID, lines, req.Restock, req.Reason)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrNotFound):
+			RespondError(w, http.StatusNotFound, "Order not found")
+		case errors.Is(err, domain.ErrInvalidRefund):
+			RespondError(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, domain.ErrNotRefundable):
+			RespondError(w, http.StatusConflict, err.Error())
+		case errors.Is(err, domain.ErrPaymentTimeout):
+			RespondError(w, http.StatusGatewayTimeout, err.Error())
+		default:
+			RespondError(w, http.StatusBadGateway, "Refund failed: "+err.Error())
+		}
+		return
+	}
+	RespondJSON(w, http.StatusCreated, refund)
+}
@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"ecommerce-api/domain"
+)
+
+// --- PASSWORD RESET HANDLERS (Public) ---
+
+func (h *APIHandler) ForgotPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Username string `json:"username"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Username == "" {
+		RespondError(w, http.StatusBadRequest, "username is required")
+		return
+	}
+
+	if err := h.Service.RequestPasswordReset(req.Username); err != nil {
+		log.Printf("Password reset request failed: %v", err)
+	}
+	// Same answer whether or not the account exists
+	RespondJSON(w, http.StatusAccepted, map[string]interface{}{
+		"message": "If the account exists, password reset instructions have been sent",
+	})
+}
+
+func (h *APIHandler) ResetPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Token       string `json:"token"`
+		NewPassword string `json:"new_password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		RespondError(w, http.StatusBadRequest, "token and new_password are required")
+		return
+	}
+
+	if err := h.Service.ResetPassword(req.Token, req.NewPassword); err != nil {
+		switch {
+		case errors.Is(err, domain.ErrInvalidToken), errors.Is(err, domain.ErrInvalidPassword):
+			RespondError(w, http.StatusBadRequest, err.Error())
+		default:
+			RespondError(w, http.StatusInternalServerError, "Could not reset password")
+		}
+		return
+	}
+	RespondJSON(w, http.StatusOK, map[string]interface{}{"message": "Password has been reset; please log in again"})
+}
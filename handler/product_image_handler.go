@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"ecommerce-api/domain"
+)
+
+// multipartOverhead leaves room for the form fields and part headers around an image.
+const multipartOverhead = 64 << 10
+
+// --- PRODUCT IMAGE HANDLERS ---
+
+// UploadProductImageHandler takes a multipart form with the file in "image"
+// and an optional "alt_text".
+func (h *APIHandler) UploadProductImageHandler(w http.ResponseWriter, r *http.Request) {
+	productID, err := PathID(r, "id")
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, "Invalid product ID")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, h.MaxImageBytes+multipartOverhead)
+	if err := r.ParseMultipartForm(h.MaxImageBytes + multipartOverhead); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			RespondError(w, http.StatusRequestEntityTooLarge, domain.ErrImageTooLarge.Error())
+			return
+		}
+		RespondError(w, http.StatusBadRequest, "Expected a multipart form with an image field")
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, header, err := r.FormFile("image")
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, "Missing image file")
+		return
+	}
+	defer file.Close()
+	content, err := io.ReadAll(file)
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, "Could not read image file")
+		return
+	}
+
+	image, err := h.Service.UploadProductImage(productID, content, header.Header.Get("Content-Type"), r.FormValue("alt_text"))
+	if err != nil {
+		respondImageError(w, err, "Could not upload image")
+		return
+	}
+	RespondJSON(w, http.StatusCreated, h.imageOutput(image))
+}
+
+// UpdateProductImageHandler changes the alt text or moves the image to a
+// 0-based position among the product's images.
+func (h *APIHandler) UpdateProductImageHandler(w http.ResponseWriter, r *http.Request) {
+	productID, err := PathID(r, "id")
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, "Invalid product ID")
+		return
+	}
+	imageID, err := PathID(r, "imageId")
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, "Invalid image ID")
+		return
+	}
+
+	var req struct {
+		AltText  *string `json:"alt_text"`
+		Position *int    `json:"position"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	product, err := h.Service.UpdateProductImage(productID, imageID, req.AltText, req.Position)
+	if err != nil {
+		respondImageError(w, err, "Could not update image")
+		return
+	}
+	RespondJSON(w, http.StatusOK, h.productDetailOutput(product))
+}
+
+func (h *APIHandler) DeleteProductImageHandler(w http.ResponseWriter, r *http.Request) {
+	productID, err := PathID(r, "id")
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, "Invalid product ID")
+		return
+	}
+	imageID, err := PathID(r, "imageId")
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, "Invalid image ID")
+		return
+	}
+
+	if err := h.Service.DeleteProductImage(productID, imageID); err != nil {
+		respondImageError(w, err, "Could not delete image")
+		return
+	}
+	RespondJSON(w, http.StatusOK, map[string]interface{}{"message": "Image deleted"})
+}
+
+func respondImageError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		RespondError(w, http.StatusNotFound, "Product or image not found")
+	case errors.Is(err, domain.ErrInvalidImage):
+		RespondError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, domain.ErrUnsupportedImage):
+		RespondError(w, http.StatusUnsupportedMediaType, err.Error())
+	case errors.Is(err, domain.ErrImageTooLarge):
+		RespondError(w, http.StatusRequestEntityTooLarge, err.Error())
+	case errors.Is(err, domain.ErrTooManyImages):
+		RespondError(w, http.StatusConflict, err.Error())
+	default:
+		RespondError(w, http.StatusInternalServerError, fallback)
+	}
+}
+
+func (h *APIHandler) imageOutput(img *domain.ProductImage) map[string]interface{} {
+	return map[string]interface{}{
+		"id":           img.ID,
+		"url":          h.Service.ImageURL(img.Key),
+		"alt_text":     img.AltText,
+		"position":     img.Position,
+		"content_type": img.ContentType,
+		"width":        img.Width,
+		"height":       img.Height,
+		"thumbnails": map[string]string{
+			"small":  h.Service.ImageURL(img.SmallKey),
+			"medium": h.Service.ImageURL(img.MediumKey),
+		},
+	}
+}
+
+func (h *APIHandler) imagesOutput(images []domain.ProductImage) []map[string]interface{} {
+	output := make([]map[string]interface{}, 0, len(images))
+	for i := range images {
+		output = append(output, h.imageOutput(&images[i]))
+	}
+	return output
+}
@@ -0,0 +1,270 @@
+package handler
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"ecommerce-api/domain"
+)
+
+// Row formats of bulk product import and export.
+const (
+	formatCSV    = "csv"
+	formatNDJSON = "ndjson"
+)
+
+// csvColumns are the columns of an export, in order. An import may put them in
+// any order and leave out all but sku, name and price_cents.
+var csvColumns = []string{"sku", "product_id", "name", "description", "price_cents", "variant_price_cents", "inventory", "options"}
+
+var requiredCSVColumns = []string{"sku", "name", "price_cents"}
+
+// maxNDJSONLine bounds one JSON line; product rows are far smaller.
+const maxNDJSONLine = 1 << 20
+
+// formulaPrefixes start cells that spreadsheets evaluate as formulas. Exported
+// text cells starting with one get a leading "'", which imports remove again.
+const formulaPrefixes = "=+-@\t\r"
+
+// spreadsheetSafe keeps a text cell from being run as a formula when the
+// export is opened in a spreadsheet.
+func spreadsheetSafe(cell string) string {
+	if cell != "" && strings.ContainsRune(formulaPrefixes, rune(cell[0])) {
+		return "'" + cell
+	}
+	return cell
+}
+
+// spreadsheetText undoes spreadsheetSafe.
+func spreadsheetText(cell string) string {
+	if len(cell) > 1 && cell[0] == '\'' && strings.ContainsRune(formulaPrefixes, rune(cell[1])) {
+		return cell[1:]
+	}
+	return cell
+}
+
+// csvRowReader reads rows from CSV with a header line. Options are written as
+// "colour=red;size=M".
+type csvRowReader struct {
+	csv     *csv.Reader
+	columns map[string]int
+}
+
+func newCSVRowReader(body io.Reader) (*csvRowReader, error) {
+	reader := csv.NewReader(body)
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("%w: the file is empty", domain.ErrInvalidImport)
+	}
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return nil, fmt.Errorf("%w: header: %v", domain.ErrInvalidImport, parseErr.Err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	known := make(map[string]bool, len(csvColumns))
+	for _, name := range csvColumns {
+		known[name] = true
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		if i == 0 {
+			name = strings.TrimPrefix(name, "\uFEFF") // Byte order mark written by spreadsheets
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !known[name] {
+			return nil, fmt.Errorf("%w: unknown column %q", domain.ErrInvalidImport, name)
+		}
+		if _, ok := columns[name]; ok {
+			return nil, fmt.Errorf("%w: column %q is given twice", domain.ErrInvalidImport, name)
+		}
+		columns[name] = i
+	}
+	for _, name := range requiredCSVColumns {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("%w: missing column %q", domain.ErrInvalidImport, name)
+		}
+	}
+	return &csvRowReader{csv: reader, columns: columns}, nil
+}
+
+func (c *csvRowReader) Next() (*domain.ProductRow, error) {
+	record, err := c.csv.Read()
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return nil, &domain.RowError{Line: parseErr.StartLine, Message: parseErr.Err.Error()}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	line, _ := c.csv.FieldPos(0)
+	field := func(name string) string {
+		if i, ok := c.columns[name]; ok {
+			return strings.TrimSpace(spreadsheetText(record[i]))
+		}
+		return ""
+	}
+	row := &domain.ProductRow{
+		Line:        line,
+		SKU:         field("sku"),
+		Name:        field("name"),
+		Description: field("description"),
+	}
+	fail := func(message string) (*domain.ProductRow, error) {
+		return nil, &domain.RowError{Line: line, SKU: row.SKU, Message: message}
+	}
+
+	if v := field("product_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 0)
+		if err != nil || id == 0 {
+			return fail("product_id must be a positive whole number")
+		}
+		row.ProductID = uint(id)
+	}
+	if row.PriceCents, err = strconv.ParseInt(field("price_cents"), 10, 64); err != nil {
+		return fail("price_cents must be a whole number")
+	}
+	if v := field("variant_price_cents"); v != "" {
+		price, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return fail("variant_price_cents must be a whole number")
+		}
+		row.VariantPriceCents = &price
+	}
+	if v := field("inventory"); v != "" {
+		inventory, err := strconv.Atoi(v)
+		if err != nil {
+			return fail("inventory must be a whole number")
+		}
+		row.Inventory = &inventory
+	}
+	if v := field("options"); v != "" {
+		row.Options = make(map[string]string)
+		for _, pair := range strings.Split(v, ";") {
+			name, value, ok := strings.Cut(pair, "=")
+			if !ok {
+				return fail(`options must look like "colour=red;size=M"`)
+			}
+			row.Options[strings.TrimSpace(name)] = strings.TrimSpace(value)
+		}
+	}
+	return row, nil
+}
+
+// ndjsonRow is one line of NDJSON import or export.
+type ndjsonRow struct {
+	SKU               string            `json:"sku"`
+	ProductID         uint              `json:"product_id,omitempty"`
+	Name              string            `json:"name"`
+	Description       string            `json:"description"`
+	PriceCents        int64             `json:"price_cents"`
+	VariantPriceCents *int64            `json:"variant_price_cents"`
+	Inventory         *int              `json:"inventory"`
+	Options           map[string]string `json:"options"`
+}
+
+// ndjsonRowReader reads one JSON object per line, skipping blank lines.
+type ndjsonRowReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func newNDJSONRowReader(body io.Reader) *ndjsonRowReader {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxNDJSONLine)
+	return &ndjsonRowReader{scanner: scanner}
+}
+
+func (n *ndjsonRowReader) Next() (*domain.ProductRow, error) {
+	for n.scanner.Scan() {
+		n.line++
+		line := bytes.TrimSpace(n.scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var raw ndjsonRow
+		decoder := json.NewDecoder(bytes.NewReader(line))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&raw); err != nil || decoder.More() {
+			return nil, &domain.RowError{Line: n.line, Message: "each line must be one JSON object with the row's fields"}
+		}
+		return &domain.ProductRow{
+			Line:              n.line,
+			SKU:               raw.SKU,
+			ProductID:         raw.ProductID,
+			Name:              raw.Name,
+			Description:       raw.Description,
+			PriceCents:        raw.PriceCents,
+			VariantPriceCents: raw.VariantPriceCents,
+			Inventory:         raw.Inventory,
+			Options:           raw.Options,
+		}, nil
+	}
+	if errors.Is(n.scanner.Err(), bufio.ErrTooLong) {
+		return nil, fmt.Errorf("%w: line %d is longer than %d bytes", domain.ErrInvalidImport, n.line+1, maxNDJSONLine)
+	}
+	if err := n.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+// csvRecord formats a row in the order of csvColumns. Numbers are written as
+// they are; only text cells can start a formula.
+func csvRecord(row *domain.ProductRow) []string {
+	var variantPrice, inventory string
+	if row.VariantPriceCents != nil {
+		variantPrice = strconv.FormatInt(*row.VariantPriceCents, 10)
+	}
+	if row.Inventory != nil {
+		inventory = strconv.Itoa(*row.Inventory)
+	}
+	names := make([]string, 0, len(row.Options))
+	for name := range row.Options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	options := make([]string, 0, len(names))
+	for _, name := range names {
+		options = append(options, name+"="+row.Options[name])
+	}
+	return []string{
+		spreadsheetSafe(row.SKU),
+		strconv.FormatUint(uint64(row.ProductID), 10),
+		spreadsheetSafe(row.Name),
+		spreadsheetSafe(row.Description),
+		strconv.FormatInt(row.PriceCents, 10),
+		variantPrice,
+		inventory,
+		spreadsheetSafe(strings.Join(options, ";")),
+	}
+}
+
+func ndjsonLine(row *domain.ProductRow) ndjsonRow {
+	options := row.Options
+	if options == nil {
+		options = map[string]string{}
+	}
+	return ndjsonRow{
+		SKU:               row.SKU,
+		ProductID:         row.ProductID,
+		Name:              row.Name,
+		Description:       row.Description,
+		PriceCents:        row.PriceCents,
+		VariantPriceCents: row.VariantPriceCents,
+		Inventory:         row.Inventory,
+		Options:           options,
+	}
+}
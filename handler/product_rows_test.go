@@ -0,0 +1,184 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"ecommerce-api/domain"
+)
+
+// readRows reads every row, collecting row errors instead of stopping at them.
+func readRows(t *testing.T, reader domain.ProductRowReader) ([]domain.ProductRow, []domain.RowError) {
+	t.Helper()
+	var rows []domain.ProductRow
+	var rowErrors []domain.RowError
+	for {
+		row, err := reader.Next()
+		if err == io.EOF {
+			return rows, rowErrors
+		}
+		var rowErr *domain.RowError
+		switch {
+		case errors.As(err, &rowErr):
+			rowErrors = append(rowErrors, *rowErr)
+		case err != nil:
+			t.Fatal(err)
+		default:
+			rows = append(rows, *row)
+		}
+	}
+}
+
+func intPtr(v int) *int       { return &v }
+func int64Ptr(v int64) *int64 { return &v }
+
+func TestCSVRowReader(t *testing.T) {
+	// Spreadsheets start the file with a byte order mark; columns come in any order
+	body := "\uFEFFName,SKU,price_cents,inventory,options,variant_price_cents,product_id\n" +
+		"T-Shirt,TEE-RED-M,1999,25,colour=red; size=M,,\n" +
+		"\"Mug, large\",MUG-L,899,,,999,7\n" +
+		"'=1+1,FORMULA-1,100,,,,\n"
+	reader, err := newCSVRowReader(strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, rowErrors := readRows(t, reader)
+	if len(rowErrors) != 0 {
+		t.Fatalf("unexpected row errors: %v", rowErrors)
+	}
+	want := []domain.ProductRow{
+		{Line: 2, SKU: "TEE-RED-M", Name: "T-Shirt", PriceCents: 1999, Inventory: intPtr(25), Options: map[string]string{"colour": "red", "size": "M"}},
+		{Line: 3, SKU: "MUG-L", ProductID: 7, Name: "Mug, large", PriceCents: 899, VariantPriceCents: int64Ptr(999)},
+		{Line: 4, SKU: "FORMULA-1", Name: "=1+1", PriceCents: 100},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("got rows\n%+v\nwant\n%+v", rows, want)
+	}
+}
+
+func TestCSVRowReaderRejectsHeader(t *testing.T) {
+	tests := map[string]string{
+		"empty file":     "",
+		"missing column": "sku,name\nA,B\n",
+		"unknown column": "sku,name,price_cents,colour\n",
+		"repeated":       "sku,name,price_cents,SKU\n",
+		"malformed":      "sku,\"name,price_cents\n",
+	}
+	for name, body := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := newCSVRowReader(strings.NewReader(body)); !errors.Is(err, domain.ErrInvalidImport) {
+				t.Errorf("got %v, want ErrInvalidImport", err)
+			}
+		})
+	}
+}
+
+func TestCSVRowReaderBadRows(t *testing.T) {
+	body := "sku,name,price_cents,product_id,variant_price_cents,inventory,options\n" +
+		"A,Alpha,12.50,,,,\n" +
+		"B,Beta,100,-3,,,\n" +
+		"C,Gamma,100,,cheap,,\n" +
+		"D,Delta,100,,,many,\n" +
+		"E,Epsilon,100,,,,red\n" +
+		"F,Zeta,100\n" +
+		"G,Eta,100,,,,\n"
+	reader, err := newCSVRowReader(strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, rowErrors := readRows(t, reader)
+
+	wantLines := []int{2, 3, 4, 5, 6, 7}
+	if len(rowErrors) != len(wantLines) {
+		t.Fatalf("got %d row errors, want %d: %v", len(rowErrors), len(wantLines), rowErrors)
+	}
+	for i, line := range wantLines {
+		if rowErrors[i].Line != line || rowErrors[i].Message == "" {
+			t.Errorf("error %d: got %+v, want one for line %d", i, rowErrors[i], line)
+		}
+	}
+	if rowErrors[0].SKU != "A" {
+		t.Errorf("error for line 2 names SKU %q, want A", rowErrors[0].SKU)
+	}
+	// Reading continues after bad rows
+	if len(rows) != 1 || rows[0].SKU != "G" || rows[0].Line != 8 {
+		t.Errorf("got rows %+v, want only G on line 8", rows)
+	}
+}
+
+func TestNDJSONRowReader(t *testing.T) {
+	body := `{"sku":"TEE-RED-M","name":"T-Shirt","price_cents":1999,"inventory":25,"options":{"size":"M"}}` + "\n" +
+		"\n" +
+		`{"sku":"MUG","name":"Mug","price_cents":899,"colour":"red"}` + "\n" +
+		`{"sku":"A"} {"sku":"B"}` + "\n" +
+		`not json` + "\n" +
+		`  {"sku":"CAP","product_id":3,"name":"Cap","price_cents":500,"variant_price_cents":450}  ` + "\n"
+	rows, rowErrors := readRows(t, newNDJSONRowReader(strings.NewReader(body)))
+
+	want := []domain.ProductRow{
+		{Line: 1, SKU: "TEE-RED-M", Name: "T-Shirt", PriceCents: 1999, Inventory: intPtr(25), Options: map[string]string{"size": "M"}},
+		{Line: 6, SKU: "CAP", ProductID: 3, Name: "Cap", PriceCents: 500, VariantPriceCents: int64Ptr(450)},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("got rows\n%+v\nwant\n%+v", rows, want)
+	}
+	var lines []int
+	for _, e := range rowErrors {
+		lines = append(lines, e.Line)
+	}
+	if !reflect.DeepEqual(lines, []int{3, 4, 5}) {
+		t.Errorf("row errors on lines %v, want [3 4 5]", lines)
+	}
+}
+
+func TestNDJSONRowReaderLineTooLong(t *testing.T) {
+	body := `{"sku":"A","name":"` + strings.Repeat("x", maxNDJSONLine) + `"}` + "\n"
+	_, err := newNDJSONRowReader(strings.NewReader(body)).Next()
+	if !errors.Is(err, domain.ErrInvalidImport) {
+		t.Errorf("got %v, want ErrInvalidImport", err)
+	}
+}
+
+func TestCSVRecordEscapesFormulas(t *testing.T) {
+	row := &domain.ProductRow{
+		SKU:         "MUG-1",
+		ProductID:   4,
+		Name:        "=HYPERLINK(\"http://evil.test\")",
+		Description: "@SUM(A1:A2)",
+		PriceCents:  899,
+		Inventory:   intPtr(0),
+		Options:     map[string]string{"+size": "L"},
+	}
+	record := csvRecord(row)
+	want := []string{"MUG-1", "4", "'=HYPERLINK(\"http://evil.test\")", "'@SUM(A1:A2)", "899", "", "0", "'+size=L"}
+	if !reflect.DeepEqual(record, want) {
+		t.Fatalf("got %q\nwant %q", record, want)
+	}
+	if got := spreadsheetSafe("-5 off"); got != "'-5 off" {
+		t.Errorf("spreadsheetSafe(\"-5 off\") = %q", got)
+	}
+	if got := spreadsheetSafe("Plain"); got != "Plain" {
+		t.Errorf("spreadsheetSafe(\"Plain\") = %q", got)
+	}
+
+	// An export reads back as the original row
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write(csvColumns)
+	w.Write(record)
+	w.Flush()
+	reader, err := newCSVRowReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, rowErrors := readRows(t, reader)
+	row.Line = 2
+	if len(rowErrors) != 0 || len(rows) != 1 || !reflect.DeepEqual(rows[0], *row) {
+		t.Errorf("round trip: got %+v, %v; want %+v", rows, rowErrors, *row)
+	}
+}
@@ -0,0 +1,183 @@
+package handler
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"ecommerce-api/domain"
+)
+
+// maxImportBytes bounds an import upload, which is read completely before it is applied.
+const maxImportBytes = 32 << 20
+
+// exportFlushRows is how many rows an export writes between flushes to the client.
+const exportFlushRows = 200
+
+// --- ADMIN PRODUCT IMPORT AND EXPORT HANDLERS ---
+
+// ImportProductsHandler takes the file as the request body, in the format
+// named by ?format= or else by the Content-Type. With ?dry_run=true the rows
+// are checked and the counts reported, but nothing is saved.
+func (h *APIHandler) ImportProductsHandler(w http.ResponseWriter, r *http.Request) {
+	claims := GetUserClaims(r)
+	if claims == nil {
+		RespondError(w, http.StatusUnauthorized, "User context missing")
+		return
+	}
+
+	dryRun := false
+	if v := r.URL.Query().Get("dry_run"); v != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(v); err != nil {
+			RespondError(w, http.StatusBadRequest, "dry_run must be true or false")
+			return
+		}
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		switch mediaType {
+		case "text/csv":
+			format = formatCSV
+		case "application/x-ndjson", "application/jsonl":
+			format = formatNDJSON
+		}
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBytes)
+	var rows domain.ProductRowReader
+	switch format {
+	case formatCSV:
+		reader, err := newCSVRowReader(r.Body)
+		if err != nil {
+			respondImportError(w, err)
+			return
+		}
+		rows = reader
+	case formatNDJSON:
+		rows = newNDJSONRowReader(r.Body)
+	default:
+		RespondError(w, http.StatusUnsupportedMediaType, "Send text/csv or application/x-ndjson, or set format to csv or ndjson")
+		return
+	}
+
+	result, err := h.Service.ImportProducts(claims.UserID, rows, dryRun)
+	if err != nil {
+		respondImportError(w, err)
+		return
+	}
+
+	rowErrors := make([]map[string]interface{}, 0, len(result.Errors))
+	for _, e := range result.Errors {
+		rowErrors = append(rowErrors, map[string]interface{}{"line": e.Line, "sku": e.SKU, "message": e.Message})
+	}
+	output := map[string]interface{}{
+		"dry_run":          result.DryRun,
+		"committed":        result.Committed,
+		"rows":             result.Rows,
+		"created_products": result.CreatedProducts,
+		"created_variants": result.CreatedVariants,
+		"updated_variants": result.UpdatedVariants,
+		"error_count":      result.ErrorCount,
+		"errors":           rowErrors,
+	}
+	if result.ErrorCount > 0 && !result.DryRun {
+		output["error"] = "Some rows are invalid; nothing was imported"
+		RespondJSON(w, http.StatusUnprocessableEntity, output)
+		return
+	}
+	RespondJSON(w, http.StatusOK, output)
+}
+
+// ExportProductsHandler streams every variant of the catalog as CSV (the
+// default) or NDJSON, in the same columns the import reads.
+func (h *APIHandler) ExportProductsHandler(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = formatCSV
+	}
+
+	var write func(row *domain.ProductRow) error
+	var finish func() error // Writes out what is buffered
+	switch format {
+	case formatCSV:
+		writer := csv.NewWriter(w)
+		wroteHeader := false
+		header := func() error {
+			if wroteHeader {
+				return nil
+			}
+			wroteHeader = true
+			return writer.Write(csvColumns)
+		}
+		write = func(row *domain.ProductRow) error {
+			if err := header(); err != nil {
+				return err
+			}
+			return writer.Write(csvRecord(row))
+		}
+		finish = func() error {
+			if err := header(); err != nil { // An empty catalog still gets its header
+				return err
+			}
+			writer.Flush()
+			return writer.Error()
+		}
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	case formatNDJSON:
+		encoder := json.NewEncoder(w)
+		write = func(row *domain.ProductRow) error { return encoder.Encode(ndjsonLine(row)) }
+		finish = func() error { return nil }
+		w.Header().Set("Content-Type", "application/x-ndjson")
+	default:
+		RespondError(w, http.StatusBadRequest, "format must be csv or ndjson")
+		return
+	}
+	w.Header().Set("Content-Disposition", `attachment; filename="products.`+format+`"`)
+
+	// Headers and status go out with the first row, so an error before it can
+	// still be reported; after it the response can only be cut short.
+	rows := 0
+	controller := http.NewResponseController(w)
+	err := h.Service.ExportProducts(func(row *domain.ProductRow) error {
+		if err := write(row); err != nil {
+			return err
+		}
+		rows++
+		if rows%exportFlushRows == 0 {
+			if err := finish(); err != nil {
+				return err
+			}
+			return controller.Flush()
+		}
+		return nil
+	})
+	if err == nil {
+		err = finish()
+	}
+	if err != nil {
+		if rows == 0 {
+			w.Header().Del("Content-Disposition")
+			RespondError(w, http.StatusInternalServerError, "Could not export products")
+			return
+		}
+		log.Printf("Product export stopped after %d rows: %v", rows, err)
+	}
+}
+
+func respondImportError(w http.ResponseWriter, err error) {
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		RespondError(w, http.StatusRequestEntityTooLarge, "Import file is too large")
+	case errors.Is(err, domain.ErrInvalidImport):
+		RespondError(w, http.StatusBadRequest, err.Error())
+	default:
+		RespondError(w, http.StatusInternalServerError, "Could not import products")
+	}
+}
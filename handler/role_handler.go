@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"ecommerce-api/domain"
+)
+
+// --- ADMIN USER & ROLE HANDLERS ---
+
+func (h *APIHandler) ListRolesHandler(w http.ResponseWriter, r *http.Request) {
+	roles, err := h.Service.ListRoles()
+	if err != nil {
+		RespondError(w, http.StatusInternalServerError, "Could not retrieve roles")
+		return
+	}
+
+	output := make([]map[string]interface{}, 0, len(roles))
+	for _, role := range roles {
+		perms := make([]domain.Permission, 0, len(role.Permissions))
+		for _, rp := range role.Permissions {
+			perms = append(perms, rp.Permission)
+		}
+		output = append(output, map[string]interface{}{
+			"name":        role.Name,
+			"description": role.Description,
+			"permissions": perms,
+		})
+	}
+	RespondJSON(w, http.StatusOK, output)
+}
+
+func (h *APIHandler) SetUserRolesHandler(w http.ResponseWriter, r *http.Request) {
+	claims := GetUserClaims(r)
+	if claims == nil {
+		RespondError(w, http.StatusUnauthorized, "User context missing")
+		return
+	}
+
+	userID, err := PathID(r, "id")
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	var req struct {
+		Roles []string `json:"roles"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	user, err := h.Service.SetUserRoles(claims, userID, req.Roles)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrNotFound):
+			RespondError(w, http.StatusNotFound, "User not found")
+		case errors.Is(err, domain.ErrUnknownRole):
+			RespondError(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, domain.ErrRoleNotGrantable):
+			RespondError(w, http.StatusForbidden, err.Error())
+		default:
+			RespondError(w, http.StatusInternalServerError, "Could not update roles")
+		}
+		return
+	}
+
+	RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"user_id":     user.ID,
+		"username":    user.Username,
+		"roles":       user.RoleNames(),
+		"permissions": user.Permissions(),
+	})
+}
+
+func (h *APIHandler) UnlockUserHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := PathID(r, "id")
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	if err := h.Service.UnlockUser(userID); err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			RespondError(w, http.StatusNotFound, "User not found")
+			return
+		}
+		RespondError(w, http.StatusInternalServerError, "Could not unlock user")
+		return
+	}
+	RespondJSON(w, http.StatusOK, map[string]interface{}{"message": "User unlocked"})
+}
@@ -2,27 +2,60 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
+
+	"ecommerce-api/domain"
 )
 
+// issueSession creates an access token and a refresh token for the user. mfa
+// is true only when the login just passed the second factor.
+func (h *APIHandler) issueSession(user *domain.User, mfa bool) (map[string]interface{}, error) {
+	token, err := h.JWTService.GenerateToken(user, mfa)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, err := h.Service.IssueRefreshToken(user.ID, mfa)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"token":         token,
+		"refresh_token": refreshToken,
+		"expires_in":    int(h.JWTService.TokenTTL().Seconds()),
+	}, nil
+}
+
 func (h *APIHandler) SignupHandler(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		Username string `json:"username"`
 		Password string `json:"password"`
+		Email    string `json:"email"` // Optional
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		RespondError(w, http.StatusBadRequest, "Invalid request payload")
 		return
 	}
 
-	user, err := h.Service.Signup(req.Username, req.Password)
+	user, err := h.Service.Signup(req.Username, req.Password, req.Email)
 	if err != nil {
+		if errors.Is(err, domain.ErrInvalidEmail) {
+			RespondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		RespondError(w, http.StatusConflict, "Username already taken or invalid input") // Be generic
 		return
 	}
 
-	token, _ := h.JWTService.GenerateToken(user.ID, user.IsAdmin)
-	RespondJSON(w, http.StatusCreated, map[string]interface{}{"message": "User created", "token": token})
+	session, err := h.issueSession(user, false)
+	if err != nil {
+		RespondError(w, http.StatusInternalServerError, "Could not create session")
+		return
+	}
+	session["message"] = "User created"
+	RespondJSON(w, http.StatusCreated, session)
 }
 
 func (h *APIHandler) LoginHandler(w http.ResponseWriter, r *http.Request) {
@@ -35,12 +68,158 @@ func (h *APIHandler) LoginHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := h.Service.Login(req.Username, req.Password)
+	user, err := h.Service.Login(req.Username, req.Password, h.ClientIP(r))
 	if err != nil {
+		if respondThrottled(w, err) {
+			return
+		}
 		RespondError(w, http.StatusUnauthorized, err.Error())
 		return
 	}
 
-	token, _ := h.JWTService.GenerateToken(user.ID, user.IsAdmin)
-	RespondJSON(w, http.StatusOK, map[string]interface{}{"message": "Login successful", "token": token, "is_admin": user.IsAdmin})
+	h.completeLogin(w, user)
+}
+
+// completeLogin issues a session, or an MFA challenge for the second step if
+// the user has 2FA on.
+func (h *APIHandler) completeLogin(w http.ResponseWriter, user *domain.User) {
+	if user.MFAEnabled() {
+		challenge, err := h.JWTService.GenerateMFAChallenge(user.ID)
+		if err != nil {
+			RespondError(w, http.StatusInternalServerError, "Could not create session")
+			return
+		}
+		RespondJSON(w, http.StatusOK, map[string]interface{}{
+			"message":      "Two-factor authentication required",
+			"mfa_required": true,
+			"mfa_token":    challenge,
+		})
+		return
+	}
+
+	h.respondLogin(w, user, false)
+}
+
+// LoginMFAHandler is the second login step: it exchanges an MFA challenge and
+// a TOTP or recovery code for a session.
+func (h *APIHandler) LoginMFAHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		MFAToken string `json:"mfa_token"`
+		Code     string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.MFAToken == "" || req.Code == "" {
+		RespondError(w, http.StatusBadRequest, "mfa_token and code are required")
+		return
+	}
+
+	userID, err := h.JWTService.ValidateMFAChallenge(req.MFAToken)
+	if err != nil {
+		RespondError(w, http.StatusUnauthorized, "Invalid or expired MFA token")
+		return
+	}
+
+	user, err := h.Service.VerifyMFA(userID, req.Code, h.ClientIP(r))
+	if err != nil {
+		if respondThrottled(w, err) {
+			return
+		}
+		if errors.Is(err, domain.ErrInvalidMFACode) || errors.Is(err, domain.ErrMFANotEnrolled) {
+			RespondError(w, http.StatusUnauthorized, err.Error())
+			return
+		}
+		RespondError(w, http.StatusInternalServerError, "Could not verify code")
+		return
+	}
+
+	h.respondLogin(w, user, true)
+}
+
+// respondThrottled answers 429 with Retry-After if err is a lockout.
+func respondThrottled(w http.ResponseWriter, err error) bool {
+	var throttled *domain.ThrottledError
+	if !errors.As(err, &throttled) {
+		return false
+	}
+	seconds := int(math.Ceil(throttled.RetryAfter.Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	RespondError(w, http.StatusTooManyRequests, domain.ErrTooManyAttempts.Error())
+	return true
+}
+
+func (h *APIHandler) respondLogin(w http.ResponseWriter, user *domain.User, mfa bool) {
+	session, err := h.issueSession(user, mfa)
+	if err != nil {
+		RespondError(w, http.StatusInternalServerError, "Could not create session")
+		return
+	}
+	session["message"] = "Login successful"
+	session["roles"] = user.RoleNames()
+	session["permissions"] = user.Permissions()
+	session["email"] = user.Email
+	session["email_verified"] = user.EmailVerified()
+	session["mfa_enabled"] = user.MFAEnabled()
+	RespondJSON(w, http.StatusOK, session)
+}
+
+func (h *APIHandler) RefreshTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		RespondError(w, http.StatusBadRequest, "refresh_token is required")
+		return
+	}
+
+	user, refreshToken, mfa, err := h.Service.RotateRefreshToken(req.RefreshToken)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidToken) || errors.Is(err, domain.ErrTokenReused) {
+			RespondError(w, http.StatusUnauthorized, err.Error())
+			return
+		}
+		RespondError(w, http.StatusInternalServerError, "Could not refresh session")
+		return
+	}
+
+	token, err := h.JWTService.GenerateToken(user, mfa)
+	if err != nil {
+		RespondError(w, http.StatusInternalServerError, "Could not refresh session")
+		return
+	}
+	RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"token":         token,
+		"refresh_token": refreshToken,
+		"expires_in":    int(h.JWTService.TokenTTL().Seconds()),
+	})
+}
+
+// LogoutHandler revokes the session of the given refresh token. Outstanding
+// access tokens stay valid until they expire, which is why they are short-lived.
+func (h *APIHandler) LogoutHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		RespondError(w, http.StatusBadRequest, "refresh_token is required")
+		return
+	}
+
+	if err := h.Service.RevokeRefreshToken(req.RefreshToken); err != nil {
+		RespondError(w, http.StatusInternalServerError, "Could not log out")
+		return
+	}
+	RespondJSON(w, http.StatusOK, map[string]interface{}{"message": "Logged out"})
+}
+
+func (h *APIHandler) LogoutAllHandler(w http.ResponseWriter, r *http.Request) {
+	claims := GetUserClaims(r)
+	if claims == nil {
+		RespondError(w, http.StatusUnauthorized, "User context missing")
+		return
+	}
+
+	if err := h.Service.RevokeAllSessions(claims.UserID); err != nil {
+		RespondError(w, http.StatusInternalServerError, "Could not log out")
+		return
+	}
+	RespondJSON(w, http.StatusOK, map[string]interface{}{"message": "Logged out of all sessions"})
 }
@@ -0,0 +1,174 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"ecommerce-api/domain"
+)
+
+// --- VARIANT HANDLERS ---
+
+func (h *APIHandler) ListOptionTypesHandler(w http.ResponseWriter, r *http.Request) {
+	optionTypes, err := h.Service.ListOptionTypes()
+	if err != nil {
+		RespondError(w, http.StatusInternalServerError, "Failed to retrieve option types")
+		return
+	}
+	output := make([]map[string]interface{}, 0, len(optionTypes))
+	for _, t := range optionTypes {
+		output = append(output, map[string]interface{}{"id": t.ID, "name": t.Name})
+	}
+	RespondJSON(w, http.StatusOK, output)
+}
+
+func (h *APIHandler) CreateOptionTypeHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	optionType, err := h.Service.CreateOptionType(req.Name)
+	if err != nil {
+		respondVariantError(w, err, "Could not create option type")
+		return
+	}
+	RespondJSON(w, http.StatusCreated, map[string]interface{}{"id": optionType.ID, "name": optionType.Name})
+}
+
+// variantRequest describes a new variant; options map option type names to values.
+type variantRequest struct {
+	SKU        string            `json:"sku"`
+	PriceCents *int64            `json:"price_cents"`
+	Inventory  int               `json:"inventory"`
+	Options    map[string]string `json:"options"`
+}
+
+func (req *variantRequest) variant() domain.ProductVariant {
+	variant := domain.ProductVariant{SKU: req.SKU, PriceCents: req.PriceCents, Inventory: req.Inventory}
+	for name, value := range req.Options {
+		variant.Options = append(variant.Options, domain.VariantOption{OptionType: domain.OptionType{Name: name}, Value: value})
+	}
+	return variant
+}
+
+func (h *APIHandler) CreateVariantHandler(w http.ResponseWriter, r *http.Request) {
+	productID, err := PathID(r, "id")
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, "Invalid product ID")
+		return
+	}
+
+	var req variantRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	variant := req.variant()
+	product, err := h.Service.CreateVariant(productID, &variant)
+	if err != nil {
+		respondVariantError(w, err, "Could not create variant")
+		return
+	}
+	RespondJSON(w, http.StatusCreated, h.productDetailOutput(product))
+}
+
+// UpdateVariantHandler changes the SKU or price override; a null price_cents
+// makes the variant use the product price again.
+func (h *APIHandler) UpdateVariantHandler(w http.ResponseWriter, r *http.Request) {
+	productID, err := PathID(r, "id")
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, "Invalid product ID")
+		return
+	}
+	variantID, err := PathID(r, "variantId")
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, "Invalid variant ID")
+		return
+	}
+
+	var req struct {
+		SKU        *string         `json:"sku"`
+		PriceCents json.RawMessage `json:"price_cents"`
+		Inventory  *int            `json:"inventory"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	if req.Inventory != nil {
+		RespondError(w, http.StatusBadRequest, "Inventory cannot be updated here; use the inventory adjustment endpoint")
+		return
+	}
+	changes := domain.VariantChanges{SKU: req.SKU}
+	switch string(req.PriceCents) {
+	case "":
+	case "null":
+		changes.ClearPrice = true
+	default:
+		if err := json.Unmarshal(req.PriceCents, &changes.PriceCents); err != nil {
+			RespondError(w, http.StatusBadRequest, "price_cents must be an integer or null")
+			return
+		}
+	}
+
+	product, err := h.Service.UpdateVariant(productID, variantID, changes)
+	if err != nil {
+		respondVariantError(w, err, "Could not update variant")
+		return
+	}
+	RespondJSON(w, http.StatusOK, h.productDetailOutput(product))
+}
+
+func (h *APIHandler) DeleteVariantHandler(w http.ResponseWriter, r *http.Request) {
+	productID, err := PathID(r, "id")
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, "Invalid product ID")
+		return
+	}
+	variantID, err := PathID(r, "variantId")
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, "Invalid variant ID")
+		return
+	}
+
+	if err := h.Service.DeleteVariant(productID, variantID); err != nil {
+		respondVariantError(w, err, "Could not delete variant")
+		return
+	}
+	RespondJSON(w, http.StatusOK, map[string]interface{}{"message": "Variant deleted"})
+}
+
+func respondVariantError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		RespondError(w, http.StatusNotFound, "Product or variant not found")
+	case errors.Is(err, domain.ErrInvalidVariant):
+		RespondError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, domain.ErrSKUTaken), errors.Is(err, domain.ErrOptionTypeTaken),
+		errors.Is(err, domain.ErrVariantHasStock), errors.Is(err, domain.ErrLastVariant):
+		RespondError(w, http.StatusConflict, err.Error())
+	default:
+		RespondError(w, http.StatusInternalServerError, fallback)
+	}
+}
+
+func variantOutput(v *domain.ProductVariant, productPriceCents int64) map[string]interface{} {
+	price := v.Price(productPriceCents)
+	return map[string]interface{}{
+		"id":          v.ID,
+		"sku":         v.SKU,
+		"price":       fmt.Sprintf("%.2f", float64(price)/100.0),
+		"price_cents": price,
+		"options":     v.OptionMap(),
+		"inventory":   v.Inventory,
+		"reserved":    v.Reserved,
+		"available":   v.Available(),
+	}
+}
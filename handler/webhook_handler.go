@@ -0,0 +1,35 @@
+package handler
+
+import (
+	"io"
+	"log"
+	"net/http"
+)
+
+// Stripe events are small; anything larger than this is not a legitimate delivery.
+const maxWebhookBodyBytes = 65536
+
+// --- PAYMENT WEBHOOK HANDLERS (Public, signature-verified) ---
+
+func (h *APIHandler) StripeWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	payload, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBodyBytes))
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, "Could not read request body")
+		return
+	}
+
+	event, err := h.Webhooks.ConstructWebhookEvent(payload, r.Header.Get("Stripe-Signature"))
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.Service.HandlePaymentEvent(event); err != nil {
+		log.Printf("Failed to process payment event %s: %v", event.ID, err)
+		// A non-2xx status makes Stripe retry the delivery later
+		RespondError(w, http.StatusInternalServerError, "Failed to process event")
+		return
+	}
+
+	RespondJSON(w, http.StatusOK, map[string]interface{}{"received": true})
+}
@@ -3,7 +3,10 @@ package main
 import (
 	"log"
 	"net/http"
+	"strings"
+	"time"
 
+	"ecommerce-api/domain"
 	"ecommerce-api/handler"
 	"ecommerce-api/repository"
 	"ecommerce-api/service"
@@ -13,6 +16,11 @@ func main() {
 	// Load configuration
 	cfg := LoadConfig()
 
+	passwordHasher, err := service.NewPasswordHasher(cfg.PasswordHashAlgorithm)
+	if err != nil {
+		log.Fatalf("Invalid PASSWORD_HASH_ALGORITHM: %v", err)
+	}
+
 	// Initialize database repository
 	repoCfg := repository.Config{
 		DBHost:     cfg.DBHost,
@@ -22,6 +30,8 @@ func main() {
 		DBPort:     cfg.DBPort,
 		AdminUser:  cfg.AdminUser,
 		AdminPass:  cfg.AdminPass,
+
+		PasswordHasher: passwordHasher,
 	}
 
 	postgresRepo, err := repository.NewPostgresRepository(repoCfg)
@@ -31,19 +41,114 @@ func main() {
 
 	// Create separate repository instances
 	userRepo := &repository.UserRepo{PostgresRepository: postgresRepo}
+	roleRepo := &repository.RoleRepo{PostgresRepository: postgresRepo}
 	productRepo := &repository.ProductRepo{PostgresRepository: postgresRepo}
+	variantRepo := &repository.VariantRepo{PostgresRepository: postgresRepo}
+	optionTypeRepo := &repository.OptionTypeRepo{PostgresRepository: postgresRepo}
+	productImageRepo := &repository.ProductImageRepo{PostgresRepository: postgresRepo}
+	categoryRepo := &repository.CategoryRepo{PostgresRepository: postgresRepo}
 	cartRepo := &repository.CartRepo{PostgresRepository: postgresRepo}
+	orderRepo := &repository.OrderRepo{PostgresRepository: postgresRepo}
+	unitOfWork := &repository.UnitOfWork{PostgresRepository: postgresRepo}
+	idempotencyRepo := &repository.IdempotencyRepo{PostgresRepository: postgresRepo}
+	reservationRepo := &repository.ReservationRepo{PostgresRepository: postgresRepo}
+	inventoryRepo := &repository.InventoryRepo{PostgresRepository: postgresRepo}
+	refreshTokenRepo := &repository.RefreshTokenRepo{PostgresRepository: postgresRepo}
+	userTokenRepo := &repository.UserTokenRepo{PostgresRepository: postgresRepo}
+	recoveryCodeRepo := &repository.RecoveryCodeRepo{PostgresRepository: postgresRepo}
+	loginThrottleRepo := &repository.LoginThrottleRepo{PostgresRepository: postgresRepo}
+	identityRepo := &repository.IdentityRepo{PostgresRepository: postgresRepo}
+	oidcStateRepo := &repository.OIDCStateRepo{PostgresRepository: postgresRepo}
+	apiKeyRepo := &repository.APIKeyRepo{PostgresRepository: postgresRepo}
 
 	// Initialize services
-	stripeSvc := service.NewStripeService(cfg.StripeKey)
-	jwtSvc := service.NewJWTService(cfg.JWTSecret)
-	ecommerceSvc := service.NewECommerceService(userRepo, productRepo, cartRepo, stripeSvc)
+	var payments service.PaymentGateway
+	switch cfg.PaymentProvider {
+	case "fake":
+		payments = service.NewFakeGateway(service.FakeScenario(cfg.FakePaymentScenario))
+		log.Printf("Using fake payment gateway (scenario: %s)", cfg.FakePaymentScenario)
+	case "stripe":
+		if cfg.StripeKey == "" {
+			log.Fatal("STRIPE_SECRET_KEY is required; set PAYMENT_PROVIDER=fake to run without Stripe")
+		}
+		payments = service.NewStripeGateway(cfg.StripeKey)
+	default:
+		log.Fatalf("Unknown PAYMENT_PROVIDER %q", cfg.PaymentProvider)
+	}
+	var oidcProviders []*service.OIDCProvider
+	for _, p := range cfg.OIDCProviders {
+		oidcProviders = append(oidcProviders, service.NewOIDCProvider(p))
+		log.Printf("OIDC login enabled for %s (%s)", p.Name, p.Issuer)
+	}
+	var blobs service.BlobStore
+	switch cfg.ImageStorage {
+	case "local":
+		blobs, err = service.NewLocalBlobStore(cfg.ImageDir, cfg.ImageBaseURL)
+	case "s3":
+		blobs, err = service.NewS3BlobStore(cfg.S3)
+		log.Printf("Storing images in S3 bucket %s", cfg.S3.Bucket)
+	default:
+		log.Fatalf("Unknown IMAGE_STORAGE %q", cfg.ImageStorage)
+	}
+	if err != nil {
+		log.Fatalf("Failed to set up image storage: %v", err)
+	}
+	webhookVerifier := service.NewStripeWebhookVerifier(cfg.StripeWebhookSecret)
+	jwtSvc := newJWTService(cfg)
+	ecommerceSvc := service.NewECommerceService(service.Dependencies{
+		Users:         userRepo,
+		Roles:         roleRepo,
+		Products:      productRepo,
+		Variants:      variantRepo,
+		OptionTypes:   optionTypeRepo,
+		ProductImages: productImageRepo,
+		Categories:    categoryRepo,
+		Carts:         cartRepo,
+		Orders:        orderRepo,
+		Reservations:  reservationRepo,
+		Inventory:     inventoryRepo,
+		RefreshTokens: refreshTokenRepo,
+		UserTokens:    userTokenRepo,
+		RecoveryCodes: recoveryCodeRepo,
+		LoginThrottle: loginThrottleRepo,
+		Identities:    identityRepo,
+		OIDCStates:    oidcStateRepo,
+		APIKeys:       apiKeyRepo,
+		UnitOfWork:    unitOfWork,
+		Payments:      payments,
+		Blobs:         blobs,
+		Hasher:        passwordHasher,
+		Notifier:      newNotifier(cfg),
+
+		MaxImageBytes:        cfg.MaxImageBytes,
+		ReservationTTL:       cfg.ReservationTTL,
+		RefreshTokenTTL:      cfg.RefreshTokenTTL,
+		PasswordResetTTL:     cfg.PasswordResetTTL,
+		EmailVerificationTTL: cfg.EmailVerificationTTL,
+		RequireVerifiedEmail: cfg.RequireVerifiedEmail,
+		MFAIssuer:            cfg.MFAIssuer,
+		LoginPolicy: service.LoginThrottlePolicy{
+			MaxFailures:   cfg.LoginMaxFailures,
+			IPMaxFailures: cfg.LoginIPMaxFailures,
+			BaseDelay:     cfg.LoginBaseDelay,
+			Lockout:       cfg.LoginLockout,
+		},
+		OIDCProviders: oidcProviders,
+	})
+
+	// Release stock held by checkouts that were never paid
+	go runReservationSweeper(ecommerceSvc, idempotencyRepo, cfg.IdempotencyKeyTTL, cfg.ReservationSweepInterval)
 
 	// Initialize handlers
 	apiHandler := &handler.APIHandler{
 		Service:    ecommerceSvc,
 		JWTService: jwtSvc,
+		Webhooks:   webhookVerifier,
+
+		TrustProxyHeaders: cfg.TrustProxyHeaders,
+		MaxImageBytes:     cfg.MaxImageBytes,
 	}
+	authn := handler.Authenticator{JWT: jwtSvc, APIKeys: ecommerceSvc}
 
 	// Setup routes
 	mux := http.NewServeMux()
@@ -63,6 +168,62 @@ func main() {
 		}
 		apiHandler.LoginHandler(w, r)
 	})
+	mux.HandleFunc("/api/login/mfa", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		apiHandler.LoginMFAHandler(w, r)
+	})
+	mux.HandleFunc("/api/auth/oidc/{provider}/start", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		apiHandler.OIDCStartHandler(w, r)
+	})
+	mux.HandleFunc("/api/auth/oidc/{provider}/callback", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		apiHandler.OIDCCallbackHandler(w, r)
+	})
+	mux.HandleFunc("/api/token/refresh", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		apiHandler.RefreshTokenHandler(w, r)
+	})
+	mux.HandleFunc("/api/logout", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		apiHandler.LogoutHandler(w, r)
+	})
+	mux.HandleFunc("/api/password/forgot", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		apiHandler.ForgotPasswordHandler(w, r)
+	})
+	mux.HandleFunc("/api/password/reset", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		apiHandler.ResetPasswordHandler(w, r)
+	})
+	mux.HandleFunc("/api/verify-email/confirm", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		apiHandler.ConfirmEmailHandler(w, r)
+	})
 	mux.HandleFunc("/api/products", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -70,28 +231,106 @@ func main() {
 		}
 		apiHandler.GetProductsHandler(w, r)
 	})
+	mux.HandleFunc("/api/products/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		apiHandler.GetProductHandler(w, r)
+	})
+	mux.HandleFunc("/api/categories", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		apiHandler.ListCategoriesHandler(w, r)
+	})
+	mux.HandleFunc("/api/webhooks/stripe", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		apiHandler.StripeWebhookHandler(w, r)
+	})
+
+	mux.HandleFunc("/.well-known/jwks.json", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		apiHandler.JWKSHandler(w, r)
+	})
 
 	// Authenticated routes (user)
+	mux.HandleFunc("/api/logout/all", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		handler.AuthMiddleware(authn, apiHandler.LogoutAllHandler, "")(w, r)
+	})
+	mux.HandleFunc("/api/verify-email/request", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		handler.AuthMiddleware(authn, apiHandler.RequestEmailVerificationHandler, "")(w, r)
+	})
+	mux.HandleFunc("/api/mfa/totp/enroll", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		handler.AuthMiddleware(authn, apiHandler.EnrollTOTPHandler, "")(w, r)
+	})
+	mux.HandleFunc("/api/mfa/totp/confirm", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		handler.AuthMiddleware(authn, apiHandler.ConfirmTOTPHandler, "")(w, r)
+	})
+	mux.HandleFunc("/api/mfa/totp/disable", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		handler.AuthMiddleware(authn, apiHandler.DisableTOTPHandler, "")(w, r)
+	})
 	mux.HandleFunc("/api/cart/add", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
-		handler.AuthMiddleware(jwtSvc, apiHandler.AddToCartHandler, false)(w, r)
+		handler.AuthMiddleware(authn, handler.IdempotencyMiddleware(idempotencyRepo, apiHandler.AddToCartHandler), "")(w, r)
 	})
 	mux.HandleFunc("/api/cart", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
-		handler.AuthMiddleware(jwtSvc, apiHandler.ViewCartHandler, false)(w, r)
+		handler.AuthMiddleware(authn, apiHandler.ViewCartHandler, "")(w, r)
 	})
 	mux.HandleFunc("/api/checkout", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
-		handler.AuthMiddleware(jwtSvc, apiHandler.CheckoutHandler, false)(w, r)
+		handler.AuthMiddleware(authn, handler.IdempotencyMiddleware(idempotencyRepo, apiHandler.CheckoutHandler), "")(w, r)
+	})
+	mux.HandleFunc("/api/orders", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		handler.AuthMiddleware(authn, apiHandler.GetOrdersHandler, "")(w, r)
+	})
+	mux.HandleFunc("/api/orders/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		handler.AuthMiddleware(authn, apiHandler.GetOrderHandler, "")(w, r)
 	})
 
 	// Admin routes
@@ -100,8 +339,175 @@ func main() {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
-		handler.AuthMiddleware(jwtSvc, apiHandler.CreateProductHandler, true)(w, r)
+		handler.AuthMiddleware(authn, handler.IdempotencyMiddleware(idempotencyRepo, apiHandler.CreateProductHandler), domain.PermissionProductsWrite)(w, r)
+	})
+	mux.HandleFunc("/api/admin/products/{id}", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPut, http.MethodPatch:
+			handler.AuthMiddleware(authn, apiHandler.UpdateProductHandler, domain.PermissionProductsWrite)(w, r)
+		case http.MethodDelete:
+			handler.AuthMiddleware(authn, apiHandler.DeleteProductHandler, domain.PermissionProductsWrite)(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/api/admin/products/{id}/restore", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		handler.AuthMiddleware(authn, apiHandler.RestoreProductHandler, domain.PermissionProductsWrite)(w, r)
+	})
+	mux.HandleFunc("/api/admin/products/{id}/inventory", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			handler.AuthMiddleware(authn, apiHandler.InventoryHistoryHandler, domain.PermissionInventoryRead)(w, r)
+		case http.MethodPost:
+			handler.AuthMiddleware(authn, handler.IdempotencyMiddleware(idempotencyRepo, apiHandler.AdjustInventoryHandler), domain.PermissionInventoryWrite)(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/api/admin/products/{id}/categories", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		handler.AuthMiddleware(authn, apiHandler.SetProductCategoriesHandler, domain.PermissionProductsWrite)(w, r)
+	})
+	mux.HandleFunc("/api/admin/products/{id}/variants", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		handler.AuthMiddleware(authn, apiHandler.CreateVariantHandler, domain.PermissionProductsWrite)(w, r)
+	})
+	mux.HandleFunc("/api/admin/products/{id}/variants/{variantId}", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPatch:
+			handler.AuthMiddleware(authn, apiHandler.UpdateVariantHandler, domain.PermissionProductsWrite)(w, r)
+		case http.MethodDelete:
+			handler.AuthMiddleware(authn, apiHandler.DeleteVariantHandler, domain.PermissionProductsWrite)(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/api/admin/products/{id}/images", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		handler.AuthMiddleware(authn, apiHandler.UploadProductImageHandler, domain.PermissionProductsWrite)(w, r)
+	})
+	mux.HandleFunc("/api/admin/products/{id}/images/{imageId}", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPatch:
+			handler.AuthMiddleware(authn, apiHandler.UpdateProductImageHandler, domain.PermissionProductsWrite)(w, r)
+		case http.MethodDelete:
+			handler.AuthMiddleware(authn, apiHandler.DeleteProductImageHandler, domain.PermissionProductsWrite)(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/api/admin/products/import", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		handler.AuthMiddleware(authn, apiHandler.ImportProductsHandler, domain.PermissionProductsWrite)(w, r)
+	})
+	mux.HandleFunc("/api/admin/products/export", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		handler.AuthMiddleware(authn, apiHandler.ExportProductsHandler, domain.PermissionProductsWrite)(w, r)
+	})
+	mux.HandleFunc("/api/admin/option-types", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			handler.AuthMiddleware(authn, apiHandler.ListOptionTypesHandler, domain.PermissionProductsWrite)(w, r)
+		case http.MethodPost:
+			handler.AuthMiddleware(authn, apiHandler.CreateOptionTypeHandler, domain.PermissionProductsWrite)(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/api/admin/categories", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		handler.AuthMiddleware(authn, apiHandler.CreateCategoryHandler, domain.PermissionProductsWrite)(w, r)
+	})
+	mux.HandleFunc("/api/admin/categories/{id}", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPut:
+			handler.AuthMiddleware(authn, apiHandler.UpdateCategoryHandler, domain.PermissionProductsWrite)(w, r)
+		case http.MethodDelete:
+			handler.AuthMiddleware(authn, apiHandler.DeleteCategoryHandler, domain.PermissionProductsWrite)(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/api/admin/orders/{id}/refunds", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		handler.AuthMiddleware(authn, handler.IdempotencyMiddleware(idempotencyRepo, apiHandler.RefundOrderHandler), domain.PermissionOrdersRefund)(w, r)
+	})
+	mux.HandleFunc("/api/admin/roles", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		handler.AuthMiddleware(authn, apiHandler.ListRolesHandler, domain.PermissionUsersManage)(w, r)
+	})
+	mux.HandleFunc("/api/admin/users/{id}/roles", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		handler.AuthMiddleware(authn, apiHandler.SetUserRolesHandler, domain.PermissionUsersManage)(w, r)
 	})
+	mux.HandleFunc("/api/admin/users/{id}/unlock", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		handler.AuthMiddleware(authn, apiHandler.UnlockUserHandler, domain.PermissionUsersManage)(w, r)
+	})
+	mux.HandleFunc("/api/admin/api-keys", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			handler.AuthMiddleware(authn, apiHandler.ListAPIKeysHandler, domain.PermissionAPIKeysManage)(w, r)
+		case http.MethodPost:
+			handler.AuthMiddleware(authn, apiHandler.CreateAPIKeyHandler, domain.PermissionAPIKeysManage)(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/api/admin/api-keys/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		handler.AuthMiddleware(authn, apiHandler.RevokeAPIKeyHandler, domain.PermissionAPIKeysManage)(w, r)
+	})
+
+	// Locally stored images are served by this server unless IMAGE_BASE_URL points elsewhere
+	if cfg.ImageStorage == "local" && strings.HasPrefix(cfg.ImageBaseURL, "/") {
+		prefix := strings.TrimSuffix(cfg.ImageBaseURL, "/") + "/"
+		media := http.StripPrefix(prefix, mediaHandler(cfg.ImageDir))
+		mux.HandleFunc(prefix, func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			media.ServeHTTP(w, r)
+		})
+	}
 
 	// Start server
 	log.Printf("Server starting on port %s", cfg.Port)
@@ -109,3 +515,96 @@ func main() {
 		log.Fatalf("Server failed to start: %v", err)
 	}
 }
+
+// mediaHandler serves the files below dir without listing directories.
+func mediaHandler(dir string) http.Handler {
+	files := http.FileServer(http.Dir(dir))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "" || strings.HasSuffix(r.URL.Path, "/") {
+			http.NotFound(w, r)
+			return
+		}
+		// Keys are never reused, so the files can be cached for good
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+		files.ServeHTTP(w, r)
+	})
+}
+
+// runReservationSweeper settles expired stock reservations and forgets
+// idempotency keys older than idempotencyTTL, once per interval.
+func runReservationSweeper(svc service.ECommerceService, idempotency domain.IdempotencyRepository, idempotencyTTL time.Duration, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if deleted, err := idempotency.DeleteCreatedBefore(time.Now().Add(-idempotencyTTL)); err != nil {
+			log.Printf("Idempotency key sweep failed: %v", err)
+		} else if deleted > 0 {
+			log.Printf("Idempotency key sweep deleted %d expired key(s)", deleted)
+		}
+
+		settled, err := svc.ReleaseExpiredReservations()
+		if err != nil {
+			log.Printf("Reservation sweep failed: %v", err)
+			continue
+		}
+		if settled > 0 {
+			log.Printf("Reservation sweep settled %d expired order(s)", settled)
+		}
+	}
+}
+
+// newNotifier picks how reset and verification tokens reach users. The log
+// notifier writes live tokens to the server log, so it needs DEV_MODE=true.
+func newNotifier(cfg Config) service.Notifier {
+	switch cfg.Notifier {
+	case "log":
+		if !cfg.DevMode {
+			log.Fatal("NOTIFIER=log writes reset tokens to the log; it is only allowed with DEV_MODE=true")
+		}
+		log.Println("Using the log notifier; reset and verification tokens are written to the log")
+		return service.NewLogNotifier()
+	case "smtp":
+		notifier, err := service.NewSMTPNotifier(cfg.SMTP)
+		if err != nil {
+			log.Fatalf("Invalid SMTP configuration: %v", err)
+		}
+		return notifier
+	default:
+		log.Fatalf("Unknown NOTIFIER %q", cfg.Notifier)
+		return nil
+	}
+}
+
+// newJWTService signs with the key directory when one is configured and falls
+// back to the shared JWT_SECRET (HS256, not published in the JWKS) otherwise.
+// Tokens without a kid, from before key rotation, verify against JWT_SECRET in
+// the fallback mode, and with a key directory only if JWT_ACCEPT_LEGACY_TOKENS is set.
+func newJWTService(cfg Config) service.JWTService {
+	var keys []*service.SigningKey
+	activeKID := cfg.JWTActiveKeyID
+	if cfg.JWTKeysDir != "" {
+		var err error
+		keys, err = service.LoadSigningKeys(cfg.JWTKeysDir)
+		if err != nil {
+			log.Fatalf("Failed to load JWT keys: %v", err)
+		}
+		log.Printf("Loaded %d JWT keys, signing with %q", len(keys), activeKID)
+		if cfg.JWTAcceptLegacyTokens {
+			log.Println("Accepting HS256 tokens without a kid, signed with JWT_SECRET")
+			keys = append(keys, service.NewHMACKey(service.LegacyKeyID, cfg.JWTSecret))
+		}
+	} else {
+		log.Println("JWT_KEYS_DIR not set; signing tokens with the shared HS256 secret")
+		if activeKID == "" {
+			activeKID = "default"
+		}
+		// Tokens issued before key IDs existed carry no kid but the same secret
+		keys = []*service.SigningKey{service.NewHMACKey(activeKID, cfg.JWTSecret), service.NewHMACKey(service.LegacyKeyID, cfg.JWTSecret)}
+	}
+
+	jwtSvc, err := service.NewJWTService(keys, activeKID, cfg.AccessTokenTTL, cfg.RequireAdminMFA)
+	if err != nil {
+		log.Fatalf("Invalid JWT configuration: %v", err)
+	}
+	return jwtSvc
+}
@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"ecommerce-api/domain"
+)
+
+type APIKeyRepo struct {
+	*PostgresRepository
+}
+
+func (r *APIKeyRepo) Create(key *domain.APIKey) error {
+	return r.DB.Create(key).Error
+}
+
+func (r *APIKeyRepo) FindAll() ([]domain.APIKey, error) {
+	var keys []domain.APIKey
+	err := r.DB.Order("created_at DESC").Find(&keys).Error
+	return keys, err
+}
+
+func (r *APIKeyRepo) FindByPrefix(prefix string) (*domain.APIKey, error) {
+	var key domain.APIKey
+	err := r.DB.Where("prefix = ?", prefix).First(&key).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, domain.ErrNotFound
+	}
+	return &key, err
+}
+
+func (r *APIKeyRepo) Revoke(id uint) (bool, error) {
+	result := r.DB.Model(&domain.APIKey{}).Where("id = ? AND revoked_at IS NULL", id).Update("revoked_at", time.Now())
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *APIKeyRepo) TouchLastUsed(id uint, at time.Time) error {
+	return r.DB.Model(&domain.APIKey{}).
+		Where("id = ? AND (last_used_at IS NULL OR last_used_at < ?)", id, at.Add(-time.Minute)).
+		UpdateColumn("last_used_at", at).Error
+}
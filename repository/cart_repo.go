@@ -2,6 +2,7 @@ package repository
 
 import (
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"ecommerce-api/domain"
 )
@@ -21,28 +22,33 @@ func (r *CartRepo) FindByUserID(userID uint) (*domain.Cart, error) {
 	return &Cart, err
 }
 
+func (r *CartRepo) FindByUserIDForUpdate(userID uint) (*domain.Cart, error) {
+	var cart domain.Cart
+	err := r.DB.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id = ?", userID).Preload("Items").First(&cart).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, domain.ErrNotFound
+	}
+	return &cart, err
+}
+
 func (r *CartRepo) Save(Cart *domain.Cart) error {
 	return r.DB.Session(&gorm.Session{FullSaveAssociations: true}).Save(Cart).Error
 }
 
+// Clear empties the user's cart. The cart row itself is kept because user_id is
+// unique and a soft-deleted row would block FindByUserID from recreating it.
 func (r *CartRepo) Clear(userID uint) error {
-	if err := r.DB.Where("user_id = ?", userID).Delete(&domain.CartItem{}).Error; err != nil {
-		return err
-	}
-	return r.DB.Where("user_id = ?", userID).Delete(&domain.Cart{}).Error
+	cartIDs := r.DB.Model(&domain.Cart{}).Select("id").Where("user_id = ?", userID)
+	return r.DB.Unscoped().Where("cart_id IN (?)", cartIDs).Delete(&domain.CartItem{}).Error
 }
 
-func (r *CartRepo) UpdateInventory(productID uint, quantityChange int) error {
+func (r *CartRepo) UpdateInventory(variantID uint, quantityChange int, reason domain.MovementReason, reference string) error {
 	return r.DB.Transaction(func(tx *gorm.DB) error {
-		result := tx.Model(&domain.Product{}).Where("id = ? AND inventory >= ?", productID, -quantityChange).
-			UpdateColumn("inventory", gorm.Expr("inventory + ?", quantityChange))
-
-		if result.Error != nil {
-			return result.Error
-		}
-		if result.RowsAffected == 0 {
-			return domain.ErrInsufficientInv // Fails if inventory would go below zero
-		}
-		return nil
+		return recordMovement(tx, &domain.InventoryMovement{
+			VariantID:      variantID,
+			Reason:         reason,
+			InventoryDelta: quantityChange,
+			Reference:      reference,
+		})
 	})
 }
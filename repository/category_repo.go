@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+
+	"ecommerce-api/domain"
+)
+
+type CategoryRepo struct {
+	*PostgresRepository
+}
+
+func (r *CategoryRepo) Create(category *domain.Category) error {
+	return r.DB.Omit("Children").Create(category).Error
+}
+
+func (r *CategoryRepo) FindAll() ([]domain.Category, error) {
+	var categories []domain.Category
+	err := r.DB.Order("name, id").Find(&categories).Error
+	return categories, err
+}
+
+func (r *CategoryRepo) FindByID(id uint) (*domain.Category, error) {
+	var category domain.Category
+	err := r.DB.First(&category, id).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, domain.ErrNotFound
+	}
+	return &category, err
+}
+
+func (r *CategoryRepo) FindByIDs(ids []uint) ([]domain.Category, error) {
+	var categories []domain.Category
+	err := r.DB.Where("id IN ?", ids).Find(&categories).Error
+	return categories, err
+}
+
+func (r *CategoryRepo) FindBySlug(slug string) (*domain.Category, error) {
+	var category domain.Category
+	err := r.DB.Where("slug = ?", slug).First(&category).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, domain.ErrNotFound
+	}
+	return &category, err
+}
+
+func (r *CategoryRepo) Update(category *domain.Category) error {
+	return r.DB.Model(category).Select("name", "slug", "parent_id").Updates(category).Error
+}
+
+// Delete is a hard delete so the slug can be reused.
+func (r *CategoryRepo) Delete(id uint) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM product_categories WHERE category_id = ?", id).Error; err != nil {
+			return err
+		}
+		result := tx.Unscoped().Delete(&domain.Category{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return domain.ErrNotFound
+		}
+		return nil
+	})
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"ecommerce-api/domain"
+)
+
+type IdempotencyRepo struct {
+	*PostgresRepository
+}
+
+func (r *IdempotencyRepo) Reserve(record *domain.IdempotencyRecord, staleBefore time.Time) (*domain.IdempotencyRecord, error) {
+	for takenOver := false; ; takenOver = true {
+		result := r.DB.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "key"}, {Name: "user_id"}},
+			DoNothing: true,
+		}).Create(record)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 1 {
+			return nil, nil
+		}
+
+		var existing domain.IdempotencyRecord
+		err := r.DB.Where("key = ? AND user_id = ?", record.Key, record.UserID).First(&existing).Error
+		if err == gorm.ErrRecordNotFound {
+			return nil, domain.ErrNotFound // Released between our insert and lookup; the client may retry
+		}
+		if err != nil || existing.CompletedAt != nil || !existing.CreatedAt.Before(staleBefore) || takenOver {
+			return &existing, err
+		}
+
+		// The request holding the key died; delete its record and insert ours
+		// once more, so of several retries racing for it only one wins
+		err = r.DB.Unscoped().Where("completed_at IS NULL").Delete(&domain.IdempotencyRecord{}, existing.ID).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (r *IdempotencyRepo) Complete(record *domain.IdempotencyRecord) error {
+	return r.DB.Save(record).Error
+}
+
+func (r *IdempotencyRepo) Release(record *domain.IdempotencyRecord) error {
+	return r.DB.Unscoped().Delete(record).Error
+}
+
+func (r *IdempotencyRepo) DeleteCreatedBefore(before time.Time) (int64, error) {
+	result := r.DB.Unscoped().Where("created_at < ?", before).Delete(&domain.IdempotencyRecord{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"ecommerce-api/domain"
+)
+
+type IdentityRepo struct {
+	*PostgresRepository
+}
+
+func (r *IdentityRepo) FindByProviderSubject(provider, subject string) (*domain.Identity, error) {
+	var identity domain.Identity
+	err := r.DB.Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, domain.ErrNotFound
+	}
+	return &identity, err
+}
+
+func (r *IdentityRepo) Create(identity *domain.Identity) error {
+	return r.DB.Create(identity).Error
+}
+
+type OIDCStateRepo struct {
+	*PostgresRepository
+}
+
+func (r *OIDCStateRepo) Create(state *domain.OIDCLoginState) error {
+	// Abandoned logins are never redeemed; clear them out as new ones start
+	if err := r.DB.Where("expires_at < ?", time.Now()).Delete(&domain.OIDCLoginState{}).Error; err != nil {
+		return err
+	}
+	return r.DB.Create(state).Error
+}
+
+func (r *OIDCStateRepo) Consume(stateHash string) (*domain.OIDCLoginState, error) {
+	var states []domain.OIDCLoginState
+	err := r.DB.Clauses(clause.Returning{}).Where("state_hash = ?", stateHash).Delete(&states).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(states) == 0 {
+		return nil, domain.ErrNotFound
+	}
+	return &states[0], nil
+}
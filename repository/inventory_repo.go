@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"ecommerce-api/domain"
+)
+
+type InventoryRepo struct {
+	*PostgresRepository
+}
+
+func (r *InventoryRepo) Record(movement *domain.InventoryMovement) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		return recordMovement(tx, movement)
+	})
+}
+
+func (r *InventoryRepo) FindByProductID(productID uint, limit int) ([]domain.InventoryMovement, error) {
+	var movements []domain.InventoryMovement
+	err := r.DB.Where("product_id = ?", productID).Order("id DESC").Limit(limit).Find(&movements).Error
+	return movements, err
+}
+
+// recordMovement is the single place stock counters change. It keeps the
+// variant's reserved between 0 and inventory, moves its product's totals by the
+// same deltas, and writes the ledger entry on the same tx. Deleted variants and
+// products are included so stock already held for them still settles.
+func recordMovement(tx *gorm.DB, movement *domain.InventoryMovement) error {
+	variant := domain.ProductVariant{Model: gorm.Model{ID: movement.VariantID}}
+	result := tx.Unscoped().Model(&variant).Clauses(clause.Returning{Columns: []clause.Column{{Name: "product_id"}, {Name: "inventory"}, {Name: "reserved"}}}).
+		Where("inventory + ? >= reserved + ? AND reserved + ? >= 0", movement.InventoryDelta, movement.ReservedDelta, movement.ReservedDelta).
+		UpdateColumns(map[string]interface{}{
+			"inventory": gorm.Expr("inventory + ?", movement.InventoryDelta),
+			"reserved":  gorm.Expr("reserved + ?", movement.ReservedDelta),
+		})
+
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		var found int64
+		if err := tx.Unscoped().Model(&domain.ProductVariant{}).Where("id = ?", movement.VariantID).Count(&found).Error; err != nil {
+			return err
+		}
+		if found == 0 {
+			return domain.ErrNotFound // Deleted variant
+		}
+		return domain.ErrInsufficientInv // Fails if stock would go below what is reserved
+	}
+
+	err := tx.Unscoped().Model(&domain.Product{}).Where("id = ?", variant.ProductID).
+		UpdateColumns(map[string]interface{}{
+			"inventory": gorm.Expr("inventory + ?", movement.InventoryDelta),
+			"reserved":  gorm.Expr("reserved + ?", movement.ReservedDelta),
+		}).Error
+	if err != nil {
+		return err
+	}
+
+	movement.ProductID = variant.ProductID
+	movement.InventoryAfter = variant.Inventory
+	movement.ReservedAfter = variant.Reserved
+	return tx.Create(movement).Error
+}
@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"time"
+
+	"ecommerce-api/domain"
+)
+
+type LoginThrottleRepo struct {
+	*PostgresRepository
+}
+
+func (r *LoginThrottleRepo) Find(subjects []string) ([]domain.LoginThrottle, error) {
+	var throttles []domain.LoginThrottle
+	err := r.DB.Where("subject IN ?", subjects).Find(&throttles).Error
+	return throttles, err
+}
+
+func (r *LoginThrottleRepo) RecordFailure(subject string, now, windowStart time.Time) (int, error) {
+	var failures int
+	// One atomic upsert, so concurrent failures are all counted
+	err := r.DB.Raw(`INSERT INTO login_throttles (subject, failures, last_failure_at)
+		VALUES (?, 1, ?)
+		ON CONFLICT (subject) DO UPDATE SET
+			failures = CASE WHEN login_throttles.last_failure_at < ? THEN 1 ELSE login_throttles.failures + 1 END,
+			last_failure_at = EXCLUDED.last_failure_at
+		RETURNING failures`, subject, now, windowStart).Scan(&failures).Error
+	return failures, err
+}
+
+func (r *LoginThrottleRepo) Block(subject string, until time.Time) error {
+	return r.DB.Model(&domain.LoginThrottle{}).Where("subject = ?", subject).Update("blocked_until", until).Error
+}
+
+func (r *LoginThrottleRepo) Reset(subject string) error {
+	return r.DB.Where("subject = ?", subject).Delete(&domain.LoginThrottle{}).Error
+}
@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"ecommerce-api/domain"
+)
+
+type OrderRepo struct {
+	*PostgresRepository
+}
+
+func (r *OrderRepo) Create(order *domain.Order) error {
+	return r.DB.Create(order).Error
+}
+
+func (r *OrderRepo) FindByID(id uint) (*domain.Order, error) {
+	var order domain.Order
+	err := r.DB.Preload("Items").Preload("Refunds.Items").First(&order, id).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, domain.ErrNotFound
+	}
+	return &order, err
+}
+
+func (r *OrderRepo) FindByIDForUpdate(id uint) (*domain.Order, error) {
+	var order domain.Order
+	err := r.DB.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Items").Preload("Refunds.Items").First(&order, id).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, domain.ErrNotFound
+	}
+	return &order, err
+}
+
+func (r *OrderRepo) FindByUserID(userID uint) ([]domain.Order, error) {
+	var orders []domain.Order
+	err := r.DB.Where("user_id = ?", userID).Preload("Items").Order("created_at DESC").Find(&orders).Error
+	return orders, err
+}
+
+func (r *OrderRepo) FindByPaymentIntentID(paymentIntentID string) (*domain.Order, error) {
+	var order domain.Order
+	err := r.DB.Where("payment_intent_id = ?", paymentIntentID).Preload("Items").First(&order).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, domain.ErrNotFound
+	}
+	return &order, err
+}
+
+func (r *OrderRepo) FindByIdempotencyKey(userID uint, key string) (*domain.Order, error) {
+	var order domain.Order
+	err := r.DB.Where("user_id = ? AND idempotency_key = ?", userID, key).Preload("Items").First(&order).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, domain.ErrNotFound
+	}
+	return &order, err
+}
+
+func (r *OrderRepo) Update(order *domain.Order) error {
+	return r.DB.Omit("Items", "Refunds").Save(order).Error
+}
+
+func (r *OrderRepo) UpdateItem(item *domain.OrderItem) error {
+	return r.DB.Save(item).Error
+}
@@ -1,8 +1,6 @@
 package repository
 
 import (
-	"crypto/sha256"
-	"encoding/hex"
 	"fmt"
 	"log"
 
@@ -24,6 +22,8 @@ type Config struct {
 	DBPort     string
 	AdminUser  string
 	AdminPass  string
+
+	PasswordHasher domain.PasswordHasher // Used to hash the seeded admin password
 }
 
 func NewPostgresRepository(cfg Config) (*PostgresRepository, error) {
@@ -36,23 +36,48 @@ func NewPostgresRepository(cfg Config) (*PostgresRepository, error) {
 	}
 
 	// AutoMigrate tables (creates tables if they don't exist)
-	err = db.AutoMigrate(&domain.User{}, &domain.Product{}, &domain.Cart{}, &domain.CartItem{})
+	err = db.AutoMigrate(&domain.Role{}, &domain.RolePermission{}, &domain.User{}, &domain.Category{}, &domain.Product{},
+		&domain.OptionType{}, &domain.ProductVariant{}, &domain.VariantOption{}, &domain.ProductImage{}, &domain.Cart{}, &domain.CartItem{},
+		&domain.Order{}, &domain.OrderItem{}, &domain.ProcessedEvent{},
+		&domain.Refund{}, &domain.RefundItem{},
+		&domain.IdempotencyRecord{}, &domain.StockReservation{},
+		&domain.InventoryMovement{}, &domain.RefreshToken{}, &domain.UserToken{}, &domain.RecoveryCode{}, &domain.LoginThrottle{},
+		&domain.Identity{}, &domain.OIDCLoginState{}, &domain.APIKey{})
 	if err != nil {
 		return nil, fmt.Errorf("failed to run migrations: %w", err)
 	}
+	if err := migrateVariants(db); err != nil {
+		return nil, fmt.Errorf("failed to migrate product variants: %w", err)
+	}
+	if err := migrateProductSearch(db); err != nil {
+		return nil, fmt.Errorf("failed to set up product search (the pg_trgm extension must be available): %w", err)
+	}
+	if err := seedRoles(db); err != nil {
+		return nil, err
+	}
+	if err := migrateAdminFlag(db); err != nil {
+		return nil, fmt.Errorf("failed to migrate admin flag: %w", err)
+	}
 	log.Println("Database connection successful and migrations complete.")
 
 	// Create admin user if it doesn't exist (Initial setup logic)
 	var admin domain.User
 	if err := db.Where("username = ?", cfg.AdminUser).First(&admin).Error; err == gorm.ErrRecordNotFound {
 		// Hash the admin password
-		hashedPassword := hashPassword(cfg.AdminPass)
+		hashedPassword, err := cfg.PasswordHasher.Hash(cfg.AdminPass)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash admin password: %w", err)
+		}
+		var superAdmin domain.Role
+		if err := db.Where("name = ?", domain.RoleSuperAdmin).First(&superAdmin).Error; err != nil {
+			return nil, fmt.Errorf("failed to load super_admin role: %w", err)
+		}
 		admin = domain.User{
 			Username: cfg.AdminUser,
 			Password: hashedPassword,
-			IsAdmin:  true,
+			Roles:    []domain.Role{superAdmin},
 		}
-		if err := db.Create(&admin).Error; err != nil {
+		if err := db.Omit("Roles.*").Create(&admin).Error; err != nil {
 			log.Printf("Failed to create admin user: %v", err)
 		} else {
 			log.Println("Admin user created.")
@@ -61,10 +86,3 @@ func NewPostgresRepository(cfg Config) (*PostgresRepository, error) {
 
 	return &PostgresRepository{DB: db}, nil
 }
-
-// hashPassword is a simple utility (use bcrypt in production!)
-func hashPassword(password string) string {
-	h := sha256.New()
-	h.Write([]byte(password))
-	return hex.EncodeToString(h.Sum(nil))
-}
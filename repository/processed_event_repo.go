@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"gorm.io/gorm/clause"
+
+	"ecommerce-api/domain"
+)
+
+type ProcessedEventRepo struct {
+	*PostgresRepository
+}
+
+func (r *ProcessedEventRepo) MarkProcessed(eventID string, eventType string) (bool, error) {
+	event := domain.ProcessedEvent{EventID: eventID, Type: eventType}
+	result := r.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "event_id"}},
+		DoNothing: true,
+	}).Create(&event)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+
+	"ecommerce-api/domain"
+)
+
+type ProductImageRepo struct {
+	*PostgresRepository
+}
+
+func (r *ProductImageRepo) Create(image *domain.ProductImage) error {
+	return r.DB.Create(image).Error
+}
+
+func (r *ProductImageRepo) FindByProductID(productID uint) ([]domain.ProductImage, error) {
+	var images []domain.ProductImage
+	err := r.DB.Where("product_id = ?", productID).Order("position, id").Find(&images).Error
+	return images, err
+}
+
+func (r *ProductImageRepo) UpdateAltText(image *domain.ProductImage) error {
+	return r.DB.Model(image).UpdateColumn("alt_text", image.AltText).Error
+}
+
+func (r *ProductImageRepo) SetPositions(productID uint, imageIDs []uint) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		for position, id := range imageIDs {
+			err := tx.Model(&domain.ProductImage{}).Where("id = ? AND product_id = ?", id, productID).
+				UpdateColumn("position", position).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Delete is a hard delete; the caller removes the files from blob storage.
+func (r *ProductImageRepo) Delete(id uint) error {
+	result := r.DB.Unscoped().Delete(&domain.ProductImage{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+// attachImages loads the images of the listed products in one query.
+func attachImages(db *gorm.DB, products []domain.Product) error {
+	if len(products) == 0 {
+		return nil
+	}
+	ids := make([]uint, len(products))
+	byID := make(map[uint]*domain.Product, len(products))
+	for i := range products {
+		ids[i] = products[i].ID
+		byID[products[i].ID] = &products[i]
+	}
+
+	var images []domain.ProductImage
+	if err := db.Where("product_id IN ?", ids).Order("position, id").Find(&images).Error; err != nil {
+		return err
+	}
+	for _, image := range images {
+		product := byID[image.ProductID]
+		product.Images = append(product.Images, image)
+	}
+	return nil
+}
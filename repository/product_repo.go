@@ -1,7 +1,13 @@
 package repository
 
 import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"ecommerce-api/domain"
 )
@@ -10,37 +16,264 @@ type ProductRepo struct {
 	*PostgresRepository
 }
 
+// Create does not touch categories; they are assigned with SetCategories.
 func (r *ProductRepo) Create(product *domain.Product) error {
-	return r.DB.Create(product).Error
+	return r.DB.Omit(clause.Associations).Create(product).Error
 }
 
-func (r *ProductRepo) FindAll(query string) ([]domain.Product, error) {
-	var products []domain.Product
-	db := r.DB
-	if query != "" {
-		db = db.Where("name ILIKE ? OR description ILIKE ?", "%"+query+"%", "%"+query+"%")
+// productCursor is the position after the last product of a page, encoded as
+// base64 JSON. It records the sort so it cannot be replayed against another
+// order, and whether the search fell back to fuzzy matching.
+type productCursor struct {
+	Sort       domain.ProductSort `json:"s"`
+	ID         uint               `json:"id"`
+	PriceCents int64              `json:"p,omitempty"`
+	Name       string             `json:"n,omitempty"`
+	Rank       float64            `json:"r,omitempty"`
+	Fuzzy      bool               `json:"f,omitempty"`
+}
+
+// rankedProduct is a product row with its search columns.
+type rankedProduct struct {
+	domain.Product
+	SearchRank    float64
+	SearchSnippet string
+}
+
+// FindAll pages with keyset pagination: each page continues after the sort key
+// of the previous one, so deep pages cost the same as the first. A search that
+// matches nothing as full text is retried as a fuzzy name search.
+func (r *ProductRepo) FindAll(query domain.ProductQuery) (*domain.ProductPage, error) {
+	var after *productCursor
+	if query.Cursor != "" {
+		var err error
+		if after, err = decodeProductCursor(query.Cursor, query.Sort); err != nil {
+			return nil, err
+		}
 	}
-	err := db.Find(&products).Error
-	return products, err
+
+	filtered := r.DB.Model(&domain.Product{})
+	if query.MinPriceCents != nil {
+		filtered = filtered.Where("price_cents >= ?", *query.MinPriceCents)
+	}
+	if query.MaxPriceCents != nil {
+		filtered = filtered.Where("price_cents <= ?", *query.MaxPriceCents)
+	}
+	if query.InStock {
+		filtered = filtered.Where("inventory > reserved")
+	}
+	if query.CategoryID != nil {
+		filtered = filtered.Where(`id IN (
+			SELECT pc.product_id FROM product_categories pc WHERE pc.category_id IN (
+				WITH RECURSIVE tree AS (
+					SELECT id FROM categories WHERE id = ?
+					UNION ALL
+					SELECT c.id FROM categories c JOIN tree ON c.parent_id = tree.id
+				) SELECT id FROM tree))`, *query.CategoryID)
+	}
+	// A new session lets each query below build on the filters independently
+	filtered = filtered.Session(&gorm.Session{})
+
+	page := &domain.ProductPage{}
+	db := filtered
+	var search *productSearch
+	if query.Search != "" {
+		search = &productSearch{terms: query.Search, fuzzy: after != nil && after.Fuzzy}
+		db = filtered.Where(search.match()).Session(&gorm.Session{})
+		if err := db.Count(&page.Total).Error; err != nil {
+			return nil, err
+		}
+		if page.Total == 0 && after == nil {
+			search.fuzzy = true
+			db = filtered.Where(search.match()).Session(&gorm.Session{})
+			if err := db.Count(&page.Total).Error; err != nil {
+				return nil, err
+			}
+		}
+		page.Approximate = search.fuzzy
+	} else if err := db.Count(&page.Total).Error; err != nil {
+		return nil, err
+	}
+
+	column, desc := productSortColumn(query.Sort)
+	if after != nil {
+		op := ">"
+		if desc {
+			op = "<"
+		}
+		switch column {
+		case "id":
+			db = db.Where("id "+op+" ?", after.ID)
+		case "price_cents":
+			db = db.Where("(price_cents, id) "+op+" (?, ?)", after.PriceCents, after.ID)
+		case "name":
+			db = db.Where("(name, id) "+op+" (?, ?)", after.Name, after.ID)
+		case "search_rank":
+			db = db.Where("(?, id) "+op+" (?, ?)", search.rank(), after.Rank, after.ID)
+		}
+	}
+
+	direction := " ASC"
+	if desc {
+		direction = " DESC"
+	}
+	order := column + direction
+	if column != "id" {
+		order += ", id" + direction
+	}
+	// Without an explicit select gorm would ask for the search columns by name
+	if search != nil {
+		db = db.Select("products.*, ? AS search_rank, ? AS search_snippet", search.rank(), search.snippet())
+	} else {
+		db = db.Select("products.*")
+	}
+	// One extra row tells whether there is a next page
+	var rows []rankedProduct
+	if err := db.Order(order).Limit(query.Limit + 1).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	if len(rows) > query.Limit {
+		rows = rows[:query.Limit]
+		last := rows[len(rows)-1]
+		page.NextCursor = encodeProductCursor(productCursor{
+			Sort:       query.Sort,
+			ID:         last.ID,
+			PriceCents: last.PriceCents,
+			Name:       last.Name,
+			Rank:       last.SearchRank,
+			Fuzzy:      page.Approximate,
+		})
+	}
+
+	page.Products = make([]domain.Product, len(rows))
+	for i, row := range rows {
+		page.Products[i] = row.Product
+		if row.SearchSnippet != "" {
+			if page.Snippets == nil {
+				page.Snippets = make(map[uint]string)
+			}
+			page.Snippets[row.ID] = highlightSnippet(row.SearchSnippet)
+		}
+	}
+	if err := attachImages(r.DB, page.Products); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+func productSortColumn(sort domain.ProductSort) (column string, desc bool) {
+	switch sort {
+	case domain.SortPriceAsc:
+		return "price_cents", false
+	case domain.SortPriceDesc:
+		return "price_cents", true
+	case domain.SortNameAsc:
+		return "name", false
+	case domain.SortNameDesc:
+		return "name", true
+	case domain.SortRelevance:
+		return "search_rank", true
+	default:
+		return "id", true // Newest first
+	}
+}
+
+func encodeProductCursor(c productCursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeProductCursor(cursor string, sort domain.ProductSort) (*productCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", domain.ErrInvalidQuery)
+	}
+	var c productCursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID == 0 {
+		return nil, fmt.Errorf("%w: malformed cursor", domain.ErrInvalidQuery)
+	}
+	if c.Sort != sort {
+		return nil, fmt.Errorf("%w: cursor belongs to a listing sorted by %q", domain.ErrInvalidQuery, c.Sort)
+	}
+	return &c, nil
 }
 
 func (r *ProductRepo) FindByID(id uint) (*domain.Product, error) {
 	var product domain.Product
-	err := r.DB.First(&product, id).Error
+	err := r.DB.Preload("Categories", func(db *gorm.DB) *gorm.DB { return db.Order("name") }).
+		Preload("Variants", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		Preload("Variants.Options.OptionType").
+		Preload("Images", func(db *gorm.DB) *gorm.DB { return db.Order("position, id") }).
+		First(&product, id).Error
 	if err == gorm.ErrRecordNotFound {
 		return nil, domain.ErrNotFound
 	}
 	return &product, err
 }
 
+func (r *ProductRepo) FindByIDs(ids []uint) ([]domain.Product, error) {
+	var products []domain.Product
+	if len(ids) == 0 {
+		return products, nil
+	}
+	err := r.DB.Where("id IN ?", ids).Find(&products).Error
+	return products, err
+}
+
+// Update only writes the catalog fields. Stock counters are left alone, since
+// they change concurrently and only through recordMovement.
 func (r *ProductRepo) Update(product *domain.Product) error {
-	return r.DB.Save(product).Error
+	result := r.DB.Model(product).Omit(clause.Associations).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "version"}, {Name: "updated_at"}}}).
+		Where("version = ?", product.Version).
+		UpdateColumns(map[string]interface{}{
+			"name":        product.Name,
+			"description": product.Description,
+			"price_cents": product.PriceCents,
+			"version":     gorm.Expr("version + 1"),
+			"updated_at":  time.Now(),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		if _, err := r.FindByID(product.ID); err != nil {
+			return err
+		}
+		return domain.ErrVersionConflict
+	}
+	return nil
 }
 
 func (r *ProductRepo) Delete(id uint) error {
-	err := r.DB.Delete(&domain.Product{}, id).Error
-	if err != nil {
-		return err
+	result := r.DB.Delete(&domain.Product{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+func (r *ProductRepo) SetCategories(productID uint, categories []domain.Category) error {
+	product := domain.Product{Model: gorm.Model{ID: productID}}
+	return r.DB.Model(&product).Omit("Categories.*").Association("Categories").Replace(categories)
+}
+
+func (r *ProductRepo) Restore(id uint) error {
+	result := r.DB.Unscoped().Model(&domain.Product{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		UpdateColumns(map[string]interface{}{
+			"deleted_at": nil,
+			"version":    gorm.Expr("version + 1"),
+			"updated_at": time.Now(),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrNotFound
 	}
 	return nil
 }
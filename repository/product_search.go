@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Text search configuration used for the search vector and for queries; both
+// must agree for stemming to line up.
+const (
+	searchConfig = "english"
+	tsQuery      = "websearch_to_tsquery('" + searchConfig + "', ?)"
+)
+
+// ts_headline delimits matched words with private-use characters, which are
+// first removed from the text itself. highlightSnippet HTML-escapes the text
+// and only then turns them into <mark> tags, so product text cannot inject HTML.
+const (
+	snippetStart    = "\uE000"
+	snippetStop     = "\uE001"
+	headlineOptions = `StartSel="` + snippetStart + `", StopSel="` + snippetStop + `", MaxFragments=2, MaxWords=25, MinWords=8`
+)
+
+var snippetMarks = strings.NewReplacer(snippetStart, "<mark>", snippetStop, "</mark>")
+
+func highlightSnippet(headline string) string {
+	return snippetMarks.Replace(html.EscapeString(headline))
+}
+
+// migrateProductSearch adds the full-text search vector and the indexes behind
+// product search. The vector is a generated column, so Postgres keeps it in
+// sync with name (weighted highest) and description on every write.
+func migrateProductSearch(db *gorm.DB) error {
+	statements := []string{
+		`CREATE EXTENSION IF NOT EXISTS pg_trgm`,
+		fmt.Sprintf(`ALTER TABLE products ADD COLUMN IF NOT EXISTS search_vector tsvector
+			GENERATED ALWAYS AS (
+				setweight(to_tsvector('%[1]s', coalesce(name, '')), 'A') ||
+				setweight(to_tsvector('%[1]s', coalesce(description, '')), 'B')
+			) STORED`, searchConfig),
+		`CREATE INDEX IF NOT EXISTS idx_products_search_vector ON products USING GIN (search_vector)`,
+		`CREATE INDEX IF NOT EXISTS idx_products_name_trgm ON products USING GIN (name gin_trgm_ops)`,
+	}
+	for _, stmt := range statements {
+		if err := db.Exec(stmt).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// productSearch builds the SQL for one search. Full-text mode parses the terms
+// with websearch_to_tsquery ("quoted phrases", -exclusions, OR) and ranks by
+// ts_rank. Fuzzy mode is the fallback for typos: it matches names by trigram
+// word similarity and ranks by how close the best matching word is.
+type productSearch struct {
+	terms string
+	fuzzy bool
+}
+
+func (s productSearch) match() clause.Expr {
+	if s.fuzzy {
+		return gorm.Expr("? <% name", s.terms)
+	}
+	return gorm.Expr("search_vector @@ "+tsQuery, s.terms)
+}
+
+func (s productSearch) rank() clause.Expr {
+	if s.fuzzy {
+		return gorm.Expr("word_similarity(?, name)", s.terms)
+	}
+	return gorm.Expr("ts_rank(search_vector, "+tsQuery+")", s.terms)
+}
+
+// snippet highlights the matched words. Fuzzy matches have no query words to
+// highlight, so they get no snippet.
+func (s productSearch) snippet() clause.Expr {
+	if s.fuzzy {
+		return gorm.Expr("''")
+	}
+	return gorm.Expr("ts_headline('"+searchConfig+"', translate(coalesce(nullif(description, ''), name), ?, ''), "+tsQuery+", ?)",
+		snippetStart+snippetStop, s.terms, headlineOptions)
+}
@@ -0,0 +1,22 @@
+package repository
+
+import "testing"
+
+func TestHighlightSnippet(t *testing.T) {
+	match := func(word string) string { return snippetStart + word + snippetStop }
+	tests := []struct {
+		headline string
+		want     string
+	}{
+		{match("Wireless") + " " + match("mouse"), "<mark>Wireless</mark> <mark>mouse</mark>"},
+		{"A " + match("mouse") + " <script>alert(1)</script>", "A <mark>mouse</mark> &lt;script&gt;alert(1)&lt;/script&gt;"},
+		{`Fits "small" & <b>large</b> ` + match("hands"), "Fits &#34;small&#34; &amp; &lt;b&gt;large&lt;/b&gt; <mark>hands</mark>"},
+		{"<mark>not a match</mark>", "&lt;mark&gt;not a match&lt;/mark&gt;"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := highlightSnippet(tt.headline); got != tt.want {
+			t.Errorf("highlightSnippet(%q) = %q, want %q", tt.headline, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"ecommerce-api/domain"
+)
+
+type RecoveryCodeRepo struct {
+	*PostgresRepository
+}
+
+func (r *RecoveryCodeRepo) ReplaceForUser(userID uint, codeHashes []string) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&domain.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		codes := make([]domain.RecoveryCode, len(codeHashes))
+		for i, hash := range codeHashes {
+			codes[i] = domain.RecoveryCode{UserID: userID, CodeHash: hash}
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+func (r *RecoveryCodeRepo) Use(userID uint, codeHash string) (bool, error) {
+	result := r.DB.Model(&domain.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", time.Now())
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *RecoveryCodeRepo) DeleteForUser(userID uint) error {
+	return r.DB.Where("user_id = ?", userID).Delete(&domain.RecoveryCode{}).Error
+}
@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"ecommerce-api/domain"
+)
+
+type RefreshTokenRepo struct {
+	*PostgresRepository
+}
+
+func (r *RefreshTokenRepo) Create(token *domain.RefreshToken) error {
+	return r.DB.Create(token).Error
+}
+
+func (r *RefreshTokenRepo) FindByHash(tokenHash string) (*domain.RefreshToken, error) {
+	var token domain.RefreshToken
+	err := r.DB.Where("token_hash = ?", tokenHash).First(&token).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, domain.ErrNotFound
+	}
+	return &token, err
+}
+
+func (r *RefreshTokenRepo) Update(token *domain.RefreshToken) error {
+	return r.DB.Save(token).Error
+}
+
+func (r *RefreshTokenRepo) Revoke(id uint) (bool, error) {
+	result := r.DB.Model(&domain.RefreshToken{}).Where("id = ? AND revoked_at IS NULL", id).Update("revoked_at", time.Now())
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *RefreshTokenRepo) RevokeFamily(familyID string) error {
+	return r.DB.Model(&domain.RefreshToken{}).Where("family_id = ? AND revoked_at IS NULL", familyID).Update("revoked_at", time.Now()).Error
+}
+
+func (r *RefreshTokenRepo) RevokeAllForUser(userID uint) error {
+	return r.DB.Model(&domain.RefreshToken{}).Where("user_id = ? AND revoked_at IS NULL", userID).Update("revoked_at", time.Now()).Error
+}
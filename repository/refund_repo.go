@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"ecommerce-api/domain"
+)
+
+type RefundRepo struct {
+	*PostgresRepository
+}
+
+func (r *RefundRepo) Create(refund *domain.Refund) error {
+	return r.DB.Create(refund).Error
+}
+
+func (r *RefundRepo) Update(refund *domain.Refund) error {
+	return r.DB.Omit("Items").Save(refund).Error
+}
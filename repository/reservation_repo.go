@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"ecommerce-api/domain"
+)
+
+type ReservationRepo struct {
+	*PostgresRepository
+}
+
+func (r *ReservationRepo) Reserve(res *domain.StockReservation) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		// Deleted variants and products can no longer be sold
+		var live int64
+		err := tx.Model(&domain.ProductVariant{}).
+			Joins("JOIN products ON products.id = product_variants.product_id AND products.deleted_at IS NULL").
+			Where("product_variants.id = ?", res.VariantID).Count(&live).Error
+		if err != nil {
+			return err
+		}
+		if live == 0 {
+			return domain.ErrInsufficientInv
+		}
+
+		err = recordMovement(tx, &domain.InventoryMovement{
+			VariantID:     res.VariantID,
+			Reason:        domain.MovementReservation,
+			ReservedDelta: res.Quantity,
+			Reference:     fmt.Sprintf("order:%d", res.OrderID),
+		})
+		if err != nil {
+			return err
+		}
+
+		res.Status = domain.ReservationActive
+		return tx.Create(res).Error
+	})
+}
+
+func (r *ReservationRepo) ConvertForOrder(orderID uint) error {
+	return r.settle(orderID, domain.ReservationConverted)
+}
+
+func (r *ReservationRepo) ReleaseForOrder(orderID uint) error {
+	return r.settle(orderID, domain.ReservationReleased)
+}
+
+// settle moves every active reservation of the order to status and removes its
+// quantity from products.reserved; converting also takes it off products.inventory.
+// Both are written to the inventory ledger.
+func (r *ReservationRepo) settle(orderID uint, status domain.ReservationStatus) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		var reservations []domain.StockReservation
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("order_id = ? AND status = ?", orderID, domain.ReservationActive).
+			Find(&reservations).Error
+		if err != nil {
+			return err
+		}
+
+		for _, res := range reservations {
+			movement := &domain.InventoryMovement{
+				VariantID:     res.VariantID,
+				Reason:        domain.MovementReservationRelease,
+				ReservedDelta: -res.Quantity,
+				Reference:     fmt.Sprintf("order:%d", orderID),
+			}
+			if status == domain.ReservationConverted {
+				movement.Reason = domain.MovementSale
+				movement.InventoryDelta = -res.Quantity
+			}
+			if err := recordMovement(tx, movement); err != nil {
+				return err
+			}
+			if err := tx.Model(&res).UpdateColumn("status", status).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *ReservationRepo) FindExpiredOrderIDs(now time.Time) ([]uint, error) {
+	var orderIDs []uint
+	err := r.DB.Model(&domain.StockReservation{}).
+		Where("status = ? AND expires_at < ?", domain.ReservationActive, now).
+		Distinct().Pluck("order_id", &orderIDs).Error
+	return orderIDs, err
+}
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"fmt"
+	"log"
+
+	"gorm.io/gorm"
+
+	"ecommerce-api/domain"
+)
+
+type RoleRepo struct {
+	*PostgresRepository
+}
+
+func (r *RoleRepo) FindAll() ([]domain.Role, error) {
+	var roles []domain.Role
+	err := r.DB.Preload("Permissions").Order("name").Find(&roles).Error
+	return roles, err
+}
+
+func (r *RoleRepo) FindByNames(names []string) ([]domain.Role, error) {
+	var roles []domain.Role
+	err := r.DB.Preload("Permissions").Where("name IN ?", names).Find(&roles).Error
+	return roles, err
+}
+
+// seedRoles creates the built-in roles that don't exist yet. Existing roles are
+// left alone so permission changes made in the database survive restarts.
+func seedRoles(db *gorm.DB) error {
+	for _, role := range domain.DefaultRoles {
+		var existing domain.Role
+		err := db.Where("name = ?", role.Name).First(&existing).Error
+		if err == nil {
+			continue
+		}
+		if err != gorm.ErrRecordNotFound {
+			return err
+		}
+		role := role
+		role.Permissions = append([]domain.RolePermission(nil), role.Permissions...)
+		if err := db.Create(&role).Error; err != nil {
+			return fmt.Errorf("failed to seed role %s: %w", role.Name, err)
+		}
+	}
+	return nil
+}
+
+// migrateAdminFlag turns the old users.is_admin flag into the super_admin role.
+func migrateAdminFlag(db *gorm.DB) error {
+	if !db.Migrator().HasColumn(&domain.User{}, "is_admin") {
+		return nil
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec(`INSERT INTO user_roles (user_id, role_id)
+			SELECT u.id, r.id FROM users u, roles r
+			WHERE u.is_admin AND r.name = ?
+			ON CONFLICT DO NOTHING`, domain.RoleSuperAdmin).Error
+		if err != nil {
+			return err
+		}
+		log.Println("Migrated is_admin users to the super_admin role.")
+		return tx.Migrator().DropColumn(&domain.User{}, "is_admin")
+	})
+}
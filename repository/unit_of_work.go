@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+
+	"ecommerce-api/domain"
+)
+
+type UnitOfWork struct {
+	*PostgresRepository
+}
+
+func (u *UnitOfWork) Do(fn func(repos domain.Repositories) error) error {
+	return u.DB.Transaction(func(tx *gorm.DB) error {
+		return fn(newRepositories(&PostgresRepository{DB: tx}))
+	})
+}
+
+// newRepositories binds every repository to the same connection (or transaction).
+func newRepositories(r *PostgresRepository) domain.Repositories {
+	return domain.Repositories{
+		Users:        &UserRepo{PostgresRepository: r},
+		Products:     &ProductRepo{PostgresRepository: r},
+		Variants:     &VariantRepo{PostgresRepository: r},
+		Carts:        &CartRepo{PostgresRepository: r},
+		Orders:       &OrderRepo{PostgresRepository: r},
+		Events:       &ProcessedEventRepo{PostgresRepository: r},
+		Refunds:      &RefundRepo{PostgresRepository: r},
+		Reservations: &ReservationRepo{PostgresRepository: r},
+		Inventory:    &InventoryRepo{PostgresRepository: r},
+
+		RefreshTokens: &RefreshTokenRepo{PostgresRepository: r},
+		UserTokens:    &UserTokenRepo{PostgresRepository: r},
+		RecoveryCodes: &RecoveryCodeRepo{PostgresRepository: r},
+		Identities:    &IdentityRepo{PostgresRepository: r},
+	}
+}
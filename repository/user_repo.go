@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"time"
+
 	"gorm.io/gorm"
 
 	"ecommerce-api/domain"
@@ -16,7 +18,7 @@ func (r *UserRepo) Create(user *domain.User) error {
 
 func (r *UserRepo) FindByUsername(username string) (*domain.User, error) {
 	var user domain.User
-	err := r.DB.Where("username = ?", username).First(&user).Error
+	err := r.DB.Preload("Roles.Permissions").Where("username = ?", username).First(&user).Error
 	if err == gorm.ErrRecordNotFound {
 		return nil, domain.ErrNotFound
 	}
@@ -25,9 +27,49 @@ func (r *UserRepo) FindByUsername(username string) (*domain.User, error) {
 
 func (r *UserRepo) FindByID(id uint) (*domain.User, error) {
 	var user domain.User
-	err := r.DB.First(&user, id).Error
+	err := r.DB.Preload("Roles.Permissions").First(&user, id).Error
 	if err == gorm.ErrRecordNotFound {
 		return nil, domain.ErrNotFound
 	}
 	return &user, err
 }
+
+func (r *UserRepo) FindByEmail(email string) (*domain.User, error) {
+	var user domain.User
+	err := r.DB.Where("email = ?", email).First(&user).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, domain.ErrNotFound
+	}
+	return &user, err
+}
+
+func (r *UserRepo) UpdatePassword(userID uint, hashedPassword string) error {
+	return r.DB.Model(&domain.User{}).Where("id = ?", userID).Update("password", hashedPassword).Error
+}
+
+func (r *UserRepo) ReplaceRoles(user *domain.User, roles []domain.Role) error {
+	if err := r.DB.Model(user).Omit("Roles.*").Association("Roles").Replace(roles); err != nil {
+		return err
+	}
+	user.Roles = roles
+	return nil
+}
+
+func (r *UserRepo) UpdateEmail(userID uint, email string) error {
+	return r.DB.Model(&domain.User{}).Where("id = ?", userID).
+		Updates(map[string]interface{}{"email": email, "verified_at": nil}).Error
+}
+
+func (r *UserRepo) MarkEmailVerified(userID uint, at time.Time) error {
+	return r.DB.Model(&domain.User{}).Where("id = ?", userID).Update("verified_at", at).Error
+}
+
+func (r *UserRepo) SetTOTP(userID uint, secret string, enabledAt *time.Time) error {
+	return r.DB.Model(&domain.User{}).Where("id = ?", userID).
+		Updates(map[string]interface{}{"totp_secret": secret, "totp_enabled_at": enabledAt, "totp_last_step": 0}).Error
+}
+
+func (r *UserRepo) AdvanceTOTPStep(userID uint, step int64) (bool, error) {
+	result := r.DB.Model(&domain.User{}).Where("id = ? AND totp_last_step < ?", userID, step).Update("totp_last_step", step)
+	return result.RowsAffected == 1, result.Error
+}
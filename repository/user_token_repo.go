@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"ecommerce-api/domain"
+)
+
+type UserTokenRepo struct {
+	*PostgresRepository
+}
+
+func (r *UserTokenRepo) Create(token *domain.UserToken) error {
+	return r.DB.Create(token).Error
+}
+
+func (r *UserTokenRepo) FindByHash(purpose domain.TokenPurpose, tokenHash string) (*domain.UserToken, error) {
+	var token domain.UserToken
+	err := r.DB.Where("purpose = ? AND token_hash = ?", purpose, tokenHash).First(&token).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, domain.ErrNotFound
+	}
+	return &token, err
+}
+
+func (r *UserTokenRepo) MarkUsed(id uint) (bool, error) {
+	result := r.DB.Model(&domain.UserToken{}).Where("id = ? AND used_at IS NULL", id).Update("used_at", time.Now())
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *UserTokenRepo) InvalidateForUser(userID uint, purpose domain.TokenPurpose) error {
+	return r.DB.Model(&domain.UserToken{}).
+		Where("user_id = ? AND purpose = ? AND used_at IS NULL AND expires_at > ?", userID, purpose, time.Now()).
+		Update("expires_at", time.Now()).Error
+}
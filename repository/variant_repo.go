@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"log"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"ecommerce-api/domain"
+)
+
+type OptionTypeRepo struct {
+	*PostgresRepository
+}
+
+func (r *OptionTypeRepo) Create(optionType *domain.OptionType) error {
+	return r.DB.Create(optionType).Error
+}
+
+func (r *OptionTypeRepo) FindAll() ([]domain.OptionType, error) {
+	var optionTypes []domain.OptionType
+	err := r.DB.Order("name").Find(&optionTypes).Error
+	return optionTypes, err
+}
+
+func (r *OptionTypeRepo) FindByNames(names []string) ([]domain.OptionType, error) {
+	var optionTypes []domain.OptionType
+	err := r.DB.Where("name IN ?", names).Find(&optionTypes).Error
+	return optionTypes, err
+}
+
+type VariantRepo struct {
+	*PostgresRepository
+}
+
+func (r *VariantRepo) Create(variant *domain.ProductVariant) error {
+	return r.DB.Omit("Options.OptionType").Create(variant).Error
+}
+
+func (r *VariantRepo) FindByID(id uint) (*domain.ProductVariant, error) {
+	var variant domain.ProductVariant
+	err := r.DB.Preload("Options.OptionType").
+		Joins("JOIN products ON products.id = product_variants.product_id AND products.deleted_at IS NULL").
+		First(&variant, "product_variants.id = ?", id).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, domain.ErrNotFound
+	}
+	return &variant, err
+}
+
+func (r *VariantRepo) FindByProductID(productID uint) ([]domain.ProductVariant, error) {
+	var variants []domain.ProductVariant
+	err := r.DB.Preload("Options.OptionType").Where("product_id = ?", productID).Order("id").Find(&variants).Error
+	return variants, err
+}
+
+// FindBySKU includes variants of deleted products, since their SKUs stay taken.
+func (r *VariantRepo) FindBySKU(sku string) (*domain.ProductVariant, error) {
+	var variant domain.ProductVariant
+	err := r.DB.Preload("Options.OptionType").Where("sku = ?", sku).First(&variant).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, domain.ErrNotFound
+	}
+	return &variant, err
+}
+
+func (r *VariantRepo) FindBySKUs(skus []string) ([]domain.ProductVariant, error) {
+	var variants []domain.ProductVariant
+	if len(skus) == 0 {
+		return variants, nil
+	}
+	err := r.DB.Preload("Options.OptionType").Where("sku IN ?", skus).Find(&variants).Error
+	return variants, err
+}
+
+func (r *VariantRepo) FindAfter(afterID uint, limit int) ([]domain.ProductVariant, error) {
+	var variants []domain.ProductVariant
+	err := r.DB.Preload("Options.OptionType").
+		Joins("JOIN products ON products.id = product_variants.product_id AND products.deleted_at IS NULL").
+		Where("product_variants.id > ?", afterID).Order("product_variants.id").Limit(limit).
+		Find(&variants).Error
+	return variants, err
+}
+
+func (r *VariantRepo) Update(variant *domain.ProductVariant) error {
+	return r.DB.Model(variant).Omit(clause.Associations).Select("sku", "price_cents").Updates(variant).Error
+}
+
+// Delete is a hard delete so the SKU can be reused. Order items, reservations
+// and the ledger keep the variant ID they recorded.
+func (r *VariantRepo) Delete(id uint) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		var variant domain.ProductVariant
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&variant, id).Error
+		if err == gorm.ErrRecordNotFound {
+			return domain.ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if variant.Inventory != 0 || variant.Reserved != 0 {
+			return domain.ErrVariantHasStock
+		}
+
+		if err := tx.Where("variant_id = ?", id).Delete(&domain.VariantOption{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&variant).Error
+	})
+}
+
+// migrateVariants gives every product without variants a default variant
+// holding its stock, with the SKU "P<product id>", and points existing cart
+// items, orders, reservations, refunds and ledger entries at it.
+func migrateVariants(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Exec(`INSERT INTO product_variants (created_at, updated_at, product_id, sku, inventory, reserved)
+			SELECT now(), now(), p.id, 'P' || p.id, p.inventory, p.reserved FROM products p
+			WHERE NOT EXISTS (SELECT 1 FROM product_variants v WHERE v.product_id = p.id)`)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		for _, table := range []string{"cart_items", "order_items", "stock_reservations", "refund_items", "inventory_movements"} {
+			err := tx.Exec(`UPDATE ` + table + ` t SET variant_id = v.id FROM product_variants v
+				WHERE (t.variant_id IS NULL OR t.variant_id = 0) AND v.product_id = t.product_id AND v.sku = 'P' || t.product_id`).Error
+			if err != nil {
+				return err
+			}
+		}
+		err := tx.Exec(`UPDATE cart_items t SET sku = 'P' || t.product_id WHERE t.sku IS NULL OR t.sku = ''`).Error
+		if err != nil {
+			return err
+		}
+		log.Printf("Created default variants for %d products.", result.RowsAffected)
+		return nil
+	})
+}
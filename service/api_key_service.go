@@ -0,0 +1,134 @@
+package service
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"ecommerce-api/domain"
+)
+
+const apiKeyPrefix = "eck_"
+
+// --- API keys ---
+
+// CreateAPIKey issues a key with the given scopes and returns it with its
+// plaintext, which cannot be retrieved again. Creators can only grant scopes
+// they hold themselves.
+func (s *ServiceImpl) CreateAPIKey(creator *domain.Claims, name string, scopes []domain.Permission, expiresAt *time.Time) (*domain.APIKey, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, "", fmt.Errorf("%w: name is required", domain.ErrInvalidAPIKeyRequest)
+	}
+	if len(scopes) == 0 {
+		return nil, "", fmt.Errorf("%w: at least one scope is required", domain.ErrInvalidAPIKeyRequest)
+	}
+	for _, scope := range scopes {
+		if !knownPermission(scope) {
+			return nil, "", fmt.Errorf("%w: unknown scope %q", domain.ErrInvalidAPIKeyRequest, scope)
+		}
+		if !creator.HasPermission(scope) {
+			return nil, "", fmt.Errorf("%w: you do not hold %q", domain.ErrInvalidAPIKeyRequest, scope)
+		}
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return nil, "", fmt.Errorf("%w: expiry must be in the future", domain.ErrInvalidAPIKeyRequest)
+	}
+
+	prefixBytes, err := randomBytes(6)
+	if err != nil {
+		return nil, "", err
+	}
+	secret, err := randomToken()
+	if err != nil {
+		return nil, "", err
+	}
+	prefix := apiKeyPrefix + fmt.Sprintf("%x", prefixBytes)
+	plaintext := prefix + "_" + secret
+
+	key := &domain.APIKey{
+		Name:        name,
+		Prefix:      prefix,
+		KeyHash:     hashToken(plaintext),
+		Scopes:      scopes,
+		CreatedByID: creator.UserID,
+		ExpiresAt:   expiresAt,
+	}
+	if err := s.apiKeyRepo.Create(key); err != nil {
+		return nil, "", err
+	}
+	return key, plaintext, nil
+}
+
+func (s *ServiceImpl) ListAPIKeys() ([]domain.APIKey, error) {
+	return s.apiKeyRepo.FindAll()
+}
+
+func (s *ServiceImpl) RevokeAPIKey(id uint) error {
+	revoked, err := s.apiKeyRepo.Revoke(id)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+// AuthenticateAPIKey checks a key from the X-API-Key header and returns claims
+// for it. The key only grants scopes its creator still holds, so demoting or
+// removing a staff member also limits the keys they made.
+func (s *ServiceImpl) AuthenticateAPIKey(plaintext string) (*domain.Claims, error) {
+	// The lookup prefix is hex and ends at the first "_"; the secret after it
+	// is base64url and may contain "_" itself
+	rest, ok := strings.CutPrefix(plaintext, apiKeyPrefix)
+	idx := strings.Index(rest, "_")
+	if !ok || idx <= 0 {
+		return nil, domain.ErrInvalidAPIKey
+	}
+	key, err := s.apiKeyRepo.FindByPrefix(apiKeyPrefix + rest[:idx])
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, domain.ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if subtle.ConstantTimeCompare([]byte(key.KeyHash), []byte(hashToken(plaintext))) != 1 ||
+		key.RevokedAt != nil || (key.ExpiresAt != nil && now.After(*key.ExpiresAt)) {
+		return nil, domain.ErrInvalidAPIKey
+	}
+
+	creator, err := s.userRepo.FindByID(key.CreatedByID)
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, domain.ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, err
+	}
+	held := &domain.Claims{Permissions: creator.Permissions()}
+	claims := &domain.Claims{UserID: key.CreatedByID, APIKeyID: key.ID}
+	for _, scope := range key.Scopes {
+		if held.HasPermission(scope) {
+			claims.Permissions = append(claims.Permissions, scope)
+		}
+	}
+
+	if err := s.apiKeyRepo.TouchLastUsed(key.ID, now); err != nil {
+		log.Printf("Failed to record use of API key %d: %v", key.ID, err)
+	}
+	return claims, nil
+}
+
+func knownPermission(p domain.Permission) bool {
+	for _, known := range domain.AllPermissions {
+		if p == known {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"ecommerce-api/domain"
+)
+
+// memoryAPIKeyRepo is an in-memory domain.APIKeyRepository.
+type memoryAPIKeyRepo struct {
+	keys []*domain.APIKey
+}
+
+func (r *memoryAPIKeyRepo) Create(key *domain.APIKey) error {
+	key.ID = uint(len(r.keys) + 1)
+	r.keys = append(r.keys, key)
+	return nil
+}
+
+func (r *memoryAPIKeyRepo) FindAll() ([]domain.APIKey, error) {
+	all := make([]domain.APIKey, len(r.keys))
+	for i, key := range r.keys {
+		all[i] = *key
+	}
+	return all, nil
+}
+
+func (r *memoryAPIKeyRepo) FindByPrefix(prefix string) (*domain.APIKey, error) {
+	for _, key := range r.keys {
+		if key.Prefix == prefix {
+			copied := *key
+			return &copied, nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (r *memoryAPIKeyRepo) Revoke(id uint) (bool, error) {
+	for _, key := range r.keys {
+		if key.ID == id && key.RevokedAt == nil {
+			now := time.Now()
+			key.RevokedAt = &now
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *memoryAPIKeyRepo) TouchLastUsed(id uint, at time.Time) error {
+	return nil
+}
+
+// staffUsers returns every user as holding products:write.
+type staffUsers struct{ domain.UserRepository }
+
+func (staffUsers) FindByID(id uint) (*domain.User, error) {
+	user := &domain.User{Username: "staff", Roles: []domain.Role{{
+		Name:        "catalog",
+		Permissions: []domain.RolePermission{{Permission: domain.PermissionProductsWrite}},
+	}}}
+	user.ID = id
+	return user, nil
+}
+
+func TestAuthenticateAPIKey(t *testing.T) {
+	s := &ServiceImpl{apiKeyRepo: &memoryAPIKeyRepo{}, userRepo: staffUsers{}}
+	creator := &domain.Claims{UserID: 3, Permissions: []domain.Permission{domain.PermissionProductsWrite}}
+	scopes := []domain.Permission{domain.PermissionProductsWrite}
+
+	// About half of all secrets contain "_", so this covers both kinds
+	var plaintexts []string
+	for i := 0; i < 200; i++ {
+		key, plaintext, err := s.CreateAPIKey(creator, "integration", scopes, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		plaintexts = append(plaintexts, plaintext)
+		claims, err := s.AuthenticateAPIKey(plaintext)
+		if err != nil {
+			t.Fatalf("key %d (%s): %v", i, plaintext, err)
+		}
+		if claims.UserID != 3 || claims.APIKeyID != key.ID || !claims.HasPermission(domain.PermissionProductsWrite) {
+			t.Fatalf("key %d: got claims %+v", i, claims)
+		}
+	}
+
+	valid := plaintexts[0]
+	lookup := valid[:len(apiKeyPrefix)+12] // "eck_" and 6 hex-encoded bytes
+	wrong := valid[:len(valid)-1] + "A"
+	if wrong == valid {
+		wrong = valid[:len(valid)-1] + "B"
+	}
+	rejected := map[string]string{
+		"empty":          "",
+		"no eck_":        strings.TrimPrefix(valid, apiKeyPrefix),
+		"eck_ only":      apiKeyPrefix,
+		"lookup only":    lookup,
+		"empty secret":   lookup + "_",
+		"wrong secret":   wrong,
+		"unknown lookup": apiKeyPrefix + "000000000000_secret",
+	}
+	for name, plaintext := range rejected {
+		if _, err := s.AuthenticateAPIKey(plaintext); !errors.Is(err, domain.ErrInvalidAPIKey) {
+			t.Errorf("%s: got %v, want ErrInvalidAPIKey", name, err)
+		}
+	}
+
+	if err := s.RevokeAPIKey(1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.AuthenticateAPIKey(valid); !errors.Is(err, domain.ErrInvalidAPIKey) {
+		t.Errorf("revoked key: got %v, want ErrInvalidAPIKey", err)
+	}
+}
@@ -2,6 +2,8 @@ package service
 
 import (
 	"errors"
+	"fmt"
+	"sort"
 	"time"
 	"net/http"
 
@@ -10,46 +12,158 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// The MFA challenge only has to survive typing a code from an authenticator app.
+const (
+	mfaChallengeTTL     = 5 * time.Minute
+	mfaChallengePurpose = "mfa_challenge"
+)
+
 type AuthKey string
 const UserContextKey AuthKey = "user"
 
 type JWTService interface {
-	GenerateToken(userID uint, isAdmin bool) (string, error)
+	GenerateToken(user *domain.User, mfa bool) (string, error)
 	ValidateToken(tokenString string) (*domain.Claims, error)
-	Middleware(next http.HandlerFunc, requiredAdmin bool) http.HandlerFunc
+	TokenTTL() time.Duration
+	// GenerateMFAChallenge issues the short-lived token exchanged for a session
+	// once the second factor is verified. It is not accepted as an access token.
+	GenerateMFAChallenge(userID uint) (string, error)
+	ValidateMFAChallenge(tokenString string) (uint, error)
+	// PublicKeys returns the verification keys other services may use.
+	PublicKeys() JWKSet
+	Middleware(next http.HandlerFunc, required domain.Permission) http.HandlerFunc
 }
 
 type JWTAuthService struct {
 	JWTService
-	secret string
+	keys   map[string]*SigningKey
+	active *SigningKey
+	ttl    time.Duration
+
+	requireStaffMFA bool
 }
 
-func NewJWTService(secret string) JWTService {
-	return &JWTAuthService{secret: secret}
+// NewJWTService returns a JWT service that signs with the key identified by
+// activeKID and accepts tokens signed by any of keys. Access tokens are valid for
+// ttl. With requireStaffMFA, staff without two-factor authentication get tokens
+// without their permissions, which still lets them log in and enrol.
+func NewJWTService(keys []*SigningKey, activeKID string, ttl time.Duration, requireStaffMFA bool) (JWTService, error) {
+	s := &JWTAuthService{keys: make(map[string]*SigningKey, len(keys)), ttl: ttl, requireStaffMFA: requireStaffMFA}
+	for _, key := range keys {
+		if _, dup := s.keys[key.ID]; dup {
+			return nil, fmt.Errorf("duplicate key id %q", key.ID)
+		}
+		s.keys[key.ID] = key
+	}
+
+	if activeKID == LegacyKeyID {
+		return nil, errors.New("an active key id is required")
+	}
+	s.active = s.keys[activeKID]
+	if s.active == nil {
+		return nil, fmt.Errorf("active key %q not found", activeKID)
+	}
+	if !s.active.CanSign() {
+		return nil, fmt.Errorf("active key %q has no private key", activeKID)
+	}
+	return s, nil
 }
 
-// GenerateToken creates a signed JWT for the given user.
-func (s *JWTAuthService) GenerateToken(userID uint, isAdmin bool) (string, error) {
+// TokenTTL is how long newly issued access tokens stay valid.
+func (s *JWTAuthService) TokenTTL() time.Duration {
+	return s.ttl
+}
+
+// PublicKeys lists every asymmetric key, including retired ones, so tokens
+// issued before a rotation still verify downstream until they expire.
+func (s *JWTAuthService) PublicKeys() JWKSet {
+	set := JWKSet{Keys: []JSONWebKey{}}
+	for _, key := range s.keys {
+		if jwk, ok := key.jwk(); ok {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].KeyID < set.Keys[j].KeyID })
+	return set
+}
+
+// GenerateToken creates a JWT for the given user, signed with the active key.
+// The user's roles must be preloaded; their permissions are embedded in the token.
+// mfa records that the session passed a second factor; only the MFA challenge
+// step, and refreshes of sessions it started, may set it.
+func (s *JWTAuthService) GenerateToken(user *domain.User, mfa bool) (string, error) {
 	claims := domain.Claims{
+		UserID:      user.ID,
+		Roles:       user.RoleNames(),
+		Permissions: user.Permissions(),
+		MFA:         mfa,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(s.ttl)), // Short-lived; clients renew via refresh token
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+	if s.requireStaffMFA && !claims.MFA {
+		claims.Permissions = nil
+	}
+	return s.sign(claims)
+}
+
+// GenerateMFAChallenge creates the token for the second login step.
+func (s *JWTAuthService) GenerateMFAChallenge(userID uint) (string, error) {
+	return s.sign(domain.Claims{
 		UserID:  userID,
-		IsAdmin: isAdmin,
+		Purpose: mfaChallengePurpose,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour * 24)), // Token expires in 24 hours
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(mfaChallengeTTL)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
+	})
+}
+
+// ValidateMFAChallenge returns the user a challenge token was issued to.
+func (s *JWTAuthService) ValidateMFAChallenge(tokenString string) (uint, error) {
+	claims, err := s.parse(tokenString)
+	if err != nil {
+		return 0, err
+	}
+	if claims.Purpose != mfaChallengePurpose {
+		return 0, errors.New("not an MFA challenge token")
 	}
-	
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(s.secret))
+	return claims.UserID, nil
+}
+
+func (s *JWTAuthService) sign(claims domain.Claims) (string, error) {
+	token := jwt.NewWithClaims(s.active.Method, claims)
+	token.Header["kid"] = s.active.ID
+	return token.SignedString(s.active.signKey)
 }
 
-// ValidateToken parses and validates the JWT.
+// ValidateToken parses and validates an access token.
 func (s *JWTAuthService) ValidateToken(tokenString string) (*domain.Claims, error) {
+	claims, err := s.parse(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Purpose != "" {
+		return nil, errors.New("not an access token")
+	}
+	return claims, nil
+}
+
+// parse validates the JWT against the key named by its kid. A token without a
+// kid is checked against the LegacyKeyID key, if there is one.
+func (s *JWTAuthService) parse(tokenString string) (*domain.Claims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &domain.Claims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := s.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+		// The algorithm is fixed by the key, never by the token header
+		if token.Method.Alg() != key.Method.Alg() {
 			return nil, jwt.ErrSignatureInvalid
 		}
-		return []byte(s.secret), nil
+		return key.verifyKey, nil
 	})
 
 	if err != nil {
@@ -59,6 +173,6 @@ func (s *JWTAuthService) ValidateToken(tokenString string) (*domain.Claims, erro
 	if claims, ok := token.Claims.(*domain.Claims); ok && token.Valid {
 		return claims, nil
 	}
-	
+
 	return nil, errors.New("invalid token claims")
-}
\ No newline at end of file
+}
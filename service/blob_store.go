@@ -0,0 +1,32 @@
+package service
+
+import (
+	"errors"
+	"strings"
+)
+
+// BlobStore keeps uploaded files such as product images, independent of where
+// they are stored. Keys are slash-separated paths like "products/1/ab12/small.jpg".
+type BlobStore interface {
+	// Put stores content under key, replacing any existing object.
+	Put(key string, contentType string, content []byte) error
+	// Delete removes the object; deleting a missing key is not an error.
+	Delete(key string) error
+	// URL is where clients can download the object.
+	URL(key string) string
+}
+
+var errInvalidBlobKey = errors.New("invalid blob key")
+
+// validBlobKey rejects keys that could escape the store's root.
+func validBlobKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return false
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return false
+		}
+	}
+	return true
+}
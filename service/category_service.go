@@ -0,0 +1,168 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"ecommerce-api/domain"
+)
+
+var (
+	slugPattern = regexp.MustCompile(`^[a-z0-9]+(?:-[a-z0-9]+)*$`)
+	slugUnsafe  = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// --- Categories ---
+
+// CategoryTree returns the root categories with their descendants nested in
+// Children, each level sorted by name.
+func (s *ServiceImpl) CategoryTree() ([]domain.Category, error) {
+	categories, err := s.categoryRepo.FindAll()
+	if err != nil {
+		return nil, err
+	}
+
+	children := make(map[uint][]domain.Category)
+	var roots []domain.Category
+	for _, c := range categories {
+		if c.ParentID == nil {
+			roots = append(roots, c)
+		} else {
+			children[*c.ParentID] = append(children[*c.ParentID], c)
+		}
+	}
+	var attach func(nodes []domain.Category) []domain.Category
+	attach = func(nodes []domain.Category) []domain.Category {
+		for i := range nodes {
+			nodes[i].Children = attach(children[nodes[i].ID])
+		}
+		return nodes
+	}
+	return attach(roots), nil
+}
+
+// CreateCategory stores a new category. An empty slug is derived from the name.
+func (s *ServiceImpl) CreateCategory(category *domain.Category) error {
+	category.Children = nil
+	if err := s.validateCategory(category); err != nil {
+		return err
+	}
+	if category.ParentID != nil {
+		if _, err := s.categoryRepo.FindByID(*category.ParentID); err != nil {
+			if errors.Is(err, domain.ErrNotFound) {
+				return fmt.Errorf("%w: parent category not found", domain.ErrInvalidCategory)
+			}
+			return err
+		}
+	}
+	return s.categoryRepo.Create(category)
+}
+
+// UpdateCategory renames or moves a category. A category cannot be moved
+// below itself or one of its descendants.
+func (s *ServiceImpl) UpdateCategory(id uint, name, slug string, parentID *uint) (*domain.Category, error) {
+	category, err := s.categoryRepo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	category.Name, category.Slug, category.ParentID = name, slug, parentID
+	if err := s.validateCategory(category); err != nil {
+		return nil, err
+	}
+
+	if parentID != nil {
+		all, err := s.categoryRepo.FindAll()
+		if err != nil {
+			return nil, err
+		}
+		parents := make(map[uint]*uint, len(all))
+		for _, c := range all {
+			parents[c.ID] = c.ParentID
+		}
+		if _, ok := parents[*parentID]; !ok {
+			return nil, fmt.Errorf("%w: parent category not found", domain.ErrInvalidCategory)
+		}
+		// Walk up from the new parent; meeting the category itself means a cycle
+		for ancestor := parentID; ancestor != nil; ancestor = parents[*ancestor] {
+			if *ancestor == id {
+				return nil, fmt.Errorf("%w: a category cannot be moved below itself", domain.ErrInvalidCategory)
+			}
+		}
+	}
+
+	if err := s.categoryRepo.Update(category); err != nil {
+		return nil, err
+	}
+	return category, nil
+}
+
+// DeleteCategory removes a category without subcategories. Its products stay,
+// they just lose the assignment.
+func (s *ServiceImpl) DeleteCategory(id uint) error {
+	all, err := s.categoryRepo.FindAll()
+	if err != nil {
+		return err
+	}
+	for _, c := range all {
+		if c.ParentID != nil && *c.ParentID == id {
+			return domain.ErrCategoryHasChildren
+		}
+	}
+	return s.categoryRepo.Delete(id)
+}
+
+// SetProductCategories replaces the categories a product is listed under.
+func (s *ServiceImpl) SetProductCategories(productID uint, categoryIDs []uint) (*domain.Product, error) {
+	product, err := s.productRepo.FindByID(productID)
+	if err != nil {
+		return nil, err
+	}
+
+	categories := []domain.Category{}
+	if len(categoryIDs) > 0 {
+		categories, err = s.categoryRepo.FindByIDs(categoryIDs)
+		if err != nil {
+			return nil, err
+		}
+	}
+	found := make(map[uint]bool, len(categories))
+	for _, c := range categories {
+		found[c.ID] = true
+	}
+	for _, id := range categoryIDs {
+		if !found[id] {
+			return nil, fmt.Errorf("%w: category %d not found", domain.ErrInvalidCategory, id)
+		}
+	}
+
+	if err := s.productRepo.SetCategories(product.ID, categories); err != nil {
+		return nil, err
+	}
+	return s.productRepo.FindByID(product.ID)
+}
+
+// validateCategory normalizes name and slug and checks the slug is free.
+func (s *ServiceImpl) validateCategory(category *domain.Category) error {
+	category.Name = strings.TrimSpace(category.Name)
+	if category.Name == "" {
+		return fmt.Errorf("%w: name is required", domain.ErrInvalidCategory)
+	}
+	category.Slug = strings.TrimSpace(category.Slug)
+	if category.Slug == "" {
+		category.Slug = strings.Trim(slugUnsafe.ReplaceAllString(strings.ToLower(category.Name), "-"), "-")
+	}
+	if !slugPattern.MatchString(category.Slug) {
+		return fmt.Errorf("%w: slug must be lowercase letters, digits and single hyphens", domain.ErrInvalidCategory)
+	}
+
+	existing, err := s.categoryRepo.FindBySlug(category.Slug)
+	if err == nil && existing.ID != category.ID {
+		return domain.ErrCategorySlugTaken
+	}
+	if err != nil && !errors.Is(err, domain.ErrNotFound) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"net/mail"
+	"strings"
+	"time"
+
+	"ecommerce-api/domain"
+)
+
+// --- Email verification ---
+
+// normalizeEmail lower-cases and validates a bare address such as "a@b.com".
+func normalizeEmail(email string) (string, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return "", domain.ErrInvalidEmail
+	}
+	return email, nil
+}
+
+// RequestEmailVerification sends a verification token to the user's email. A
+// non-empty email replaces the current address, which then needs verifying.
+func (s *ServiceImpl) RequestEmailVerification(userID uint, email string) error {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return err
+	}
+
+	if email != "" {
+		email, err = normalizeEmail(email)
+		if err != nil {
+			return err
+		}
+		if user.Email == nil || *user.Email != email {
+			if err := s.changeEmail(user, email); err != nil {
+				return err
+			}
+		}
+	}
+	if user.Email == nil {
+		return domain.ErrEmailRequired
+	}
+	if user.EmailVerified() {
+		return domain.ErrEmailAlreadyVerified
+	}
+	return s.sendEmailVerification(user)
+}
+
+// ConfirmEmail redeems a verification token.
+func (s *ServiceImpl) ConfirmEmail(token string) error {
+	stored, err := s.userTokenRepo.FindByHash(domain.TokenEmailVerification, hashToken(token))
+	if errors.Is(err, domain.ErrNotFound) {
+		return domain.ErrInvalidToken
+	}
+	if err != nil {
+		return err
+	}
+	if stored.UsedAt != nil || time.Now().After(stored.ExpiresAt) {
+		return domain.ErrInvalidToken
+	}
+
+	return s.uow.Do(func(repos domain.Repositories) error {
+		won, err := repos.UserTokens.MarkUsed(stored.ID)
+		if err != nil {
+			return err
+		}
+		if !won {
+			return domain.ErrInvalidToken
+		}
+		return repos.Users.MarkEmailVerified(stored.UserID, time.Now())
+	})
+}
+
+func (s *ServiceImpl) changeEmail(user *domain.User, email string) error {
+	owner, err := s.userRepo.FindByEmail(email)
+	if err == nil && owner.ID != user.ID {
+		return domain.ErrEmailTaken
+	}
+	if err != nil && !errors.Is(err, domain.ErrNotFound) {
+		return err
+	}
+
+	err = s.uow.Do(func(repos domain.Repositories) error {
+		// Links sent to the old address must not verify the new one
+		if err := repos.UserTokens.InvalidateForUser(user.ID, domain.TokenEmailVerification); err != nil {
+			return err
+		}
+		return repos.Users.UpdateEmail(user.ID, email)
+	})
+	if err != nil {
+		return err
+	}
+	user.Email = &email
+	user.VerifiedAt = nil
+	return nil
+}
+
+func (s *ServiceImpl) sendEmailVerification(user *domain.User) error {
+	token, err := randomToken()
+	if err != nil {
+		return err
+	}
+	record := &domain.UserToken{
+		UserID:    user.ID,
+		Purpose:   domain.TokenEmailVerification,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(s.emailVerificationTTL),
+	}
+	err = s.uow.Do(func(repos domain.Repositories) error {
+		if err := repos.UserTokens.InvalidateForUser(user.ID, domain.TokenEmailVerification); err != nil {
+			return err
+		}
+		return repos.UserTokens.Create(record)
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := s.notifier.SendEmailVerification(user, token, record.ExpiresAt); err != nil {
+		log.Printf("Failed to deliver email verification for user %d: %v", user.ID, err)
+	}
+	return nil
+}
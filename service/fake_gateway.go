@@ -0,0 +1,168 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"ecommerce-api/domain"
+)
+
+// FakeScenario selects how the fake gateway responds to new payments.
+type FakeScenario string
+
+const (
+	FakeScenarioSuccess FakeScenario = "success" // Intents are created and succeed immediately
+	FakeScenarioDecline FakeScenario = "decline" // Every payment is declined
+	FakeScenarioTimeout FakeScenario = "timeout" // Calls hang for TimeoutAfter and then fail
+)
+
+// FakeGateway is an in-process PaymentGateway for local development and tests.
+// It never touches the network and keeps all state in memory.
+type FakeGateway struct {
+	// TimeoutAfter is how long the timeout scenario blocks before giving up.
+	TimeoutAfter time.Duration
+
+	mu       sync.Mutex
+	scenario FakeScenario
+	intents  map[string]*PaymentIntent
+	refunded map[string]int64 // Cents refunded so far, per intent
+}
+
+// NewFakeGateway returns a fake gateway; unknown scenarios fall back to success.
+func NewFakeGateway(scenario FakeScenario) *FakeGateway {
+	g := &FakeGateway{
+		TimeoutAfter: 3 * time.Second,
+		intents:      make(map[string]*PaymentIntent),
+		refunded:     make(map[string]int64),
+	}
+	g.SetScenario(scenario)
+	return g
+}
+
+// SetScenario switches the behaviour for subsequent calls.
+func (g *FakeGateway) SetScenario(scenario FakeScenario) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	switch scenario {
+	case FakeScenarioDecline, FakeScenarioTimeout:
+		g.scenario = scenario
+	default:
+		g.scenario = FakeScenarioSuccess
+	}
+}
+
+// simulate applies the current scenario and reports the error it produces, if any.
+func (g *FakeGateway) simulate() error {
+	g.mu.Lock()
+	scenario := g.scenario
+	g.mu.Unlock()
+
+	switch scenario {
+	case FakeScenarioDecline:
+		return domain.ErrPaymentDeclined
+	case FakeScenarioTimeout:
+		time.Sleep(g.TimeoutAfter)
+		return domain.ErrPaymentTimeout
+	}
+	return nil
+}
+
+func (g *FakeGateway) CreateIntent(req PaymentIntentRequest) (*PaymentIntent, error) {
+	if req.AmountCents <= 0 {
+		return nil, errors.New("fake gateway: amount must be positive")
+	}
+	if err := g.simulate(); err != nil {
+		return nil, err
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	id := fakeID("pi")
+	pi := &PaymentIntent{
+		ID:           id,
+		ClientSecret: id + "_secret_fake",
+		Status:       PaymentStatusSucceeded,
+		AmountCents:  req.AmountCents,
+		Currency:     req.Currency,
+	}
+	g.intents[id] = pi
+	copied := *pi
+	return &copied, nil
+}
+
+func (g *FakeGateway) CaptureIntent(intentID string) (*PaymentIntent, error) {
+	return g.transition(intentID, PaymentStatusSucceeded)
+}
+
+func (g *FakeGateway) CancelIntent(intentID string) (*PaymentIntent, error) {
+	return g.transition(intentID, PaymentStatusCanceled)
+}
+
+func (g *FakeGateway) RetrieveIntent(intentID string) (*PaymentIntent, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	pi, ok := g.intents[intentID]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	copied := *pi
+	return &copied, nil
+}
+
+func (g *FakeGateway) Refund(intentID string, amountCents int64) (*PaymentRefund, error) {
+	if err := g.simulate(); err != nil {
+		return nil, err
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	pi, ok := g.intents[intentID]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	if pi.Status != PaymentStatusSucceeded {
+		return nil, fmt.Errorf("fake gateway: cannot refund intent in status %s", pi.Status)
+	}
+
+	remaining := pi.AmountCents - g.refunded[intentID]
+	if amountCents == 0 {
+		amountCents = remaining
+	}
+	if amountCents <= 0 || amountCents > remaining {
+		return nil, fmt.Errorf("fake gateway: refund of %d exceeds remaining %d", amountCents, remaining)
+	}
+	g.refunded[intentID] += amountCents
+	return &PaymentRefund{
+		ID:          fakeID("re"),
+		IntentID:    intentID,
+		AmountCents: amountCents,
+		Status:      "succeeded",
+	}, nil
+}
+
+func (g *FakeGateway) transition(intentID string, status PaymentStatus) (*PaymentIntent, error) {
+	if err := g.simulate(); err != nil {
+		return nil, err
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	pi, ok := g.intents[intentID]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	pi.Status = status
+	copied := *pi
+	return &copied, nil
+}
+
+// fakeID returns a Stripe-looking ID that stays unique across restarts.
+func fakeID(prefix string) string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return prefix + "_fake_" + hex.EncodeToString(b)
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLocalBlobStore(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewLocalBlobStore(filepath.Join(dir, "uploads"), "/media/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := "products/1/ab12/small.jpg"
+	path := filepath.Join(dir, "uploads", "products", "1", "ab12", "small.jpg")
+
+	for _, content := range []string{"first", "second"} {
+		if err := store.Put(key, "image/jpeg", []byte(content)); err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != content {
+			t.Errorf("stored %q, want %q", data, content)
+		}
+	}
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("directory holds %d entries, want only the blob", len(entries))
+	}
+	if url := store.URL(key); url != "/media/"+key {
+		t.Errorf("URL = %s", url)
+	}
+
+	for i := 0; i < 2; i++ { // Deleting a missing blob is not an error
+		if err := store.Delete(key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("blob still exists: %v", err)
+	}
+
+	for _, bad := range []string{"", "/etc/passwd", "../outside.jpg", "products/../../outside.jpg", "products//a.jpg", `products\a.jpg`} {
+		if err := store.Put(bad, "image/jpeg", []byte("x")); err != errInvalidBlobKey {
+			t.Errorf("Put(%q): got %v, want errInvalidBlobKey", bad, err)
+		}
+		if err := store.Delete(bad); err != errInvalidBlobKey {
+			t.Errorf("Delete(%q): got %v, want errInvalidBlobKey", bad, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "outside.jpg")); !os.IsNotExist(err) {
+		t.Error("a blob was written outside the store")
+	}
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"ecommerce-api/domain"
+)
+
+// LoginThrottlePolicy configures brute-force protection. Failures are counted
+// per username and per client IP and forgotten after Lockout without a new one.
+type LoginThrottlePolicy struct {
+	// MaxFailures locks a username after this many failures. Before that each
+	// failure blocks it for BaseDelay, doubling every time. Zero disables
+	// per-username throttling.
+	MaxFailures int
+	// IPMaxFailures locks a client IP. IPs get no progressive delay, since
+	// many users can share one address. Zero disables per-IP throttling.
+	IPMaxFailures int
+	BaseDelay     time.Duration
+	Lockout       time.Duration
+}
+
+func userSubject(username string) string {
+	return "user:" + strings.ToLower(username)
+}
+
+func ipSubject(ip string) string {
+	return "ip:" + ip
+}
+
+// checkLoginThrottle returns a ThrottledError if any subject is still blocked.
+// Subjects whose kind of throttling is disabled are ignored, so lockouts from
+// before it was turned off do not linger.
+func (s *ServiceImpl) checkLoginThrottle(subjects ...string) error {
+	var enabled []string
+	for _, subject := range subjects {
+		isIP := strings.HasPrefix(subject, ipSubject(""))
+		if isIP && s.loginPolicy.IPMaxFailures > 0 || !isIP && s.loginPolicy.MaxFailures > 0 {
+			enabled = append(enabled, subject)
+		}
+	}
+	if len(enabled) == 0 {
+		return nil
+	}
+	throttles, err := s.loginThrottleRepo.Find(enabled)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var wait time.Duration
+	for _, t := range throttles {
+		if t.BlockedUntil != nil && t.BlockedUntil.After(now) && t.BlockedUntil.Sub(now) > wait {
+			wait = t.BlockedUntil.Sub(now)
+		}
+	}
+	if wait > 0 {
+		return &domain.ThrottledError{RetryAfter: wait}
+	}
+	return nil
+}
+
+// recordLoginFailure counts a failed attempt for the username and IP and blocks
+// them as the policy requires. Errors are logged: the caller is already failing.
+func (s *ServiceImpl) recordLoginFailure(username, clientIP string) {
+	p := s.loginPolicy
+	now := time.Now()
+	windowStart := now.Add(-p.Lockout)
+
+	if p.MaxFailures > 0 {
+		s.recordUserFailure(userSubject(username), now, windowStart)
+	}
+
+	if clientIP == "" || p.IPMaxFailures <= 0 {
+		return
+	}
+	subject := ipSubject(clientIP)
+	failures, err := s.loginThrottleRepo.RecordFailure(subject, now, windowStart)
+	if err != nil {
+		log.Printf("Failed to record login failure for %s: %v", subject, err)
+		return
+	}
+	if failures >= p.IPMaxFailures {
+		log.Printf("Locking %s after %d failed logins", subject, failures)
+		if err := s.loginThrottleRepo.Block(subject, now.Add(p.Lockout)); err != nil {
+			log.Printf("Failed to block %s: %v", subject, err)
+		}
+	}
+}
+
+// recordUserFailure blocks a username for a delay that doubles with each
+// failure, up to a full lockout at MaxFailures.
+func (s *ServiceImpl) recordUserFailure(subject string, now, windowStart time.Time) {
+	p := s.loginPolicy
+	failures, err := s.loginThrottleRepo.RecordFailure(subject, now, windowStart)
+	if err != nil {
+		log.Printf("Failed to record login failure for %s: %v", subject, err)
+		return
+	}
+	delay := p.Lockout
+	if failures < p.MaxFailures {
+		delay = p.BaseDelay << (failures - 1)
+		if delay <= 0 || delay > p.Lockout {
+			delay = p.Lockout
+		}
+	} else {
+		log.Printf("Locking %s after %d failed logins", subject, failures)
+	}
+	if err := s.loginThrottleRepo.Block(subject, now.Add(delay)); err != nil {
+		log.Printf("Failed to block %s: %v", subject, err)
+	}
+}
+
+func (s *ServiceImpl) resetLoginThrottle(username string) {
+	if s.loginPolicy.MaxFailures <= 0 {
+		return
+	}
+	if err := s.loginThrottleRepo.Reset(userSubject(username)); err != nil {
+		log.Printf("Failed to reset login throttle for %s: %v", username, err)
+	}
+}
+
+// UnlockUser clears the failed-login count and lockout of an account.
+func (s *ServiceImpl) UnlockUser(userID uint) error {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return err
+	}
+	return s.loginThrottleRepo.Reset(userSubject(user.Username))
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"ecommerce-api/domain"
+)
+
+// memoryThrottleRepo is an in-memory domain.LoginThrottleRepository.
+type memoryThrottleRepo struct {
+	throttles map[string]*domain.LoginThrottle
+}
+
+func newMemoryThrottleRepo() *memoryThrottleRepo {
+	return &memoryThrottleRepo{throttles: map[string]*domain.LoginThrottle{}}
+}
+
+func (r *memoryThrottleRepo) Find(subjects []string) ([]domain.LoginThrottle, error) {
+	var found []domain.LoginThrottle
+	for _, subject := range subjects {
+		if t, ok := r.throttles[subject]; ok {
+			found = append(found, *t)
+		}
+	}
+	return found, nil
+}
+
+func (r *memoryThrottleRepo) RecordFailure(subject string, now, windowStart time.Time) (int, error) {
+	t, ok := r.throttles[subject]
+	if !ok || t.LastFailureAt.Before(windowStart) {
+		t = &domain.LoginThrottle{Subject: subject}
+		r.throttles[subject] = t
+	}
+	t.Failures++
+	t.LastFailureAt = now
+	return t.Failures, nil
+}
+
+func (r *memoryThrottleRepo) Block(subject string, until time.Time) error {
+	r.throttles[subject].BlockedUntil = &until
+	return nil
+}
+
+func (r *memoryThrottleRepo) Reset(subject string) error {
+	delete(r.throttles, subject)
+	return nil
+}
+
+func TestLoginThrottleLimitsAreIndependent(t *testing.T) {
+	tests := []struct {
+		name          string
+		policy        LoginThrottlePolicy
+		wantUserBlock bool
+		wantIPBlock   bool
+	}{
+		{"both enabled", LoginThrottlePolicy{MaxFailures: 3, IPMaxFailures: 3}, true, true},
+		{"account lockout disabled", LoginThrottlePolicy{MaxFailures: 0, IPMaxFailures: 3}, false, true},
+		{"IP lockout disabled", LoginThrottlePolicy{MaxFailures: 3, IPMaxFailures: 0}, true, false},
+		{"both disabled", LoginThrottlePolicy{}, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.policy.BaseDelay = time.Second
+			tt.policy.Lockout = time.Minute
+			s := &ServiceImpl{loginPolicy: tt.policy, loginThrottleRepo: newMemoryThrottleRepo()}
+			for i := 0; i < 3; i++ {
+				s.recordLoginFailure("alice", "203.0.113.7")
+			}
+
+			err := s.checkLoginThrottle(userSubject("alice"))
+			if got := errors.Is(err, domain.ErrTooManyAttempts); got != tt.wantUserBlock {
+				t.Errorf("username blocked = %v, want %v (err %v)", got, tt.wantUserBlock, err)
+			}
+			err = s.checkLoginThrottle(userSubject("bob"), ipSubject("203.0.113.7"))
+			if got := errors.Is(err, domain.ErrTooManyAttempts); got != tt.wantIPBlock {
+				t.Errorf("IP blocked = %v, want %v (err %v)", got, tt.wantIPBlock, err)
+			}
+		})
+	}
+}
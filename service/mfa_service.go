@@ -0,0 +1,178 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"ecommerce-api/domain"
+)
+
+const recoveryCodeCount = 10
+
+// --- Two-factor authentication ---
+
+// EnrollTOTP generates a new TOTP secret for the user. It only takes effect
+// once ConfirmTOTP proves the authenticator app was set up correctly.
+func (s *ServiceImpl) EnrollTOTP(userID uint) (string, string, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return "", "", err
+	}
+	if user.MFAEnabled() {
+		return "", "", domain.ErrMFAAlreadyEnabled
+	}
+
+	secret, err := newTOTPSecret()
+	if err != nil {
+		return "", "", err
+	}
+	if err := s.userRepo.SetTOTP(user.ID, secret, nil); err != nil {
+		return "", "", err
+	}
+	return secret, totpURI(s.mfaIssuer, user.Username, secret), nil
+}
+
+// ConfirmTOTP enables TOTP after checking a first code, and returns recovery
+// codes in plaintext; they cannot be shown again. Every existing session is
+// revoked, so all remaining refresh tokens were issued after passing MFA.
+func (s *ServiceImpl) ConfirmTOTP(userID uint, code string) ([]string, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.MFAEnabled() {
+		return nil, domain.ErrMFAAlreadyEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, domain.ErrMFANotEnrolled
+	}
+	step, ok := verifyTOTP(user.TOTPSecret, code, time.Now())
+	if !ok {
+		return nil, domain.ErrInvalidMFACode
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	err = s.uow.Do(func(repos domain.Repositories) error {
+		now := time.Now()
+		if err := repos.Users.SetTOTP(user.ID, user.TOTPSecret, &now); err != nil {
+			return err
+		}
+		if _, err := repos.Users.AdvanceTOTPStep(user.ID, step); err != nil {
+			return err
+		}
+		if err := repos.RecoveryCodes.ReplaceForUser(user.ID, hashes); err != nil {
+			return err
+		}
+		return repos.RefreshTokens.RevokeAllForUser(user.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// DisableTOTP turns TOTP off after checking the current password and a current
+// code or recovery code, so a stolen access token alone cannot remove 2FA.
+// Wrong attempts count towards the username's login throttle.
+func (s *ServiceImpl) DisableTOTP(userID uint, password, code string) error {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return err
+	}
+	if !user.MFAEnabled() {
+		return domain.ErrMFANotEnrolled
+	}
+	if err := s.checkLoginThrottle(userSubject(user.Username)); err != nil {
+		return err
+	}
+	ok, _, err := s.hasher.Verify(password, user.Password)
+	if err != nil || !ok {
+		s.recordLoginFailure(user.Username, "")
+		return domain.ErrInvalidCredentials
+	}
+	if err := s.verifySecondFactor(user, code); err != nil {
+		if errors.Is(err, domain.ErrInvalidMFACode) {
+			s.recordLoginFailure(user.Username, "")
+		}
+		return err
+	}
+
+	return s.uow.Do(func(repos domain.Repositories) error {
+		if err := repos.Users.SetTOTP(user.ID, "", nil); err != nil {
+			return err
+		}
+		return repos.RecoveryCodes.DeleteForUser(user.ID)
+	})
+}
+
+// VerifyMFA completes a two-step login and returns the user to issue a session
+// for. Wrong codes count towards the same brute-force limits as passwords.
+func (s *ServiceImpl) VerifyMFA(userID uint, code, clientIP string) (*domain.User, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, domain.ErrInvalidMFACode
+	}
+	if !user.MFAEnabled() {
+		return nil, domain.ErrMFANotEnrolled
+	}
+	if err := s.checkLoginThrottle(userSubject(user.Username), ipSubject(clientIP)); err != nil {
+		return nil, err
+	}
+	if err := s.verifySecondFactor(user, code); err != nil {
+		if errors.Is(err, domain.ErrInvalidMFACode) {
+			s.recordLoginFailure(user.Username, clientIP)
+		}
+		return nil, err
+	}
+	s.resetLoginThrottle(user.Username)
+	return user, nil
+}
+
+// verifySecondFactor accepts a TOTP code or, failing that, an unused recovery code.
+func (s *ServiceImpl) verifySecondFactor(user *domain.User, code string) error {
+	code = strings.TrimSpace(code)
+	if step, ok := verifyTOTP(user.TOTPSecret, code, time.Now()); ok {
+		fresh, err := s.userRepo.AdvanceTOTPStep(user.ID, step)
+		if err != nil {
+			return err
+		}
+		if !fresh {
+			return domain.ErrInvalidMFACode // Replayed code
+		}
+		return nil
+	}
+
+	used, err := s.recoveryCodeRepo.Use(user.ID, hashToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return domain.ErrInvalidMFACode
+	}
+	return nil
+}
+
+// newRecoveryCodes returns codes formatted like "ABCD-EFGH-IJKL-MNOP" (80 bits
+// each) together with the hashes to store.
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		b, err := randomBytes(10)
+		if err != nil {
+			return nil, nil, err
+		}
+		raw := totpEncoding.EncodeToString(b)
+		codes[i] = raw[0:4] + "-" + raw[4:8] + "-" + raw[8:12] + "-" + raw[12:16]
+		hashes[i] = hashToken(raw)
+	}
+	return codes, hashes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
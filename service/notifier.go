@@ -0,0 +1,35 @@
+package service
+
+import (
+	"log"
+	"time"
+
+	"ecommerce-api/domain"
+)
+
+// Notifier delivers out-of-band messages to users. Implementations decide the
+// channel (email, SMS, ...); the service only hands over the secret to deliver.
+type Notifier interface {
+	SendPasswordReset(user *domain.User, token string, expiresAt time.Time) error
+	SendEmailVerification(user *domain.User, token string, expiresAt time.Time) error
+}
+
+// LogNotifier writes messages to the server log. It is meant for development
+// only, since the log then contains live reset tokens.
+type LogNotifier struct{}
+
+func NewLogNotifier() *LogNotifier {
+	return &LogNotifier{}
+}
+
+func (n *LogNotifier) SendPasswordReset(user *domain.User, token string, expiresAt time.Time) error {
+	log.Printf("[notifier] password reset for user %d (%s): token=%s expires=%s",
+		user.ID, user.Username, token, expiresAt.Format(time.RFC3339))
+	return nil
+}
+
+func (n *LogNotifier) SendEmailVerification(user *domain.User, token string, expiresAt time.Time) error {
+	log.Printf("[notifier] email verification for user %d (%s): token=%s expires=%s",
+		user.ID, *user.Email, token, expiresAt.Format(time.RFC3339))
+	return nil
+}
@@ -0,0 +1,169 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"ecommerce-api/domain"
+)
+
+// How long a user has to finish signing in at the provider.
+const oidcLoginTTL = 10 * time.Minute
+
+var usernameUnsafe = regexp.MustCompile(`[^a-z0-9._-]+`)
+
+// --- External login (OIDC) ---
+
+// StartOIDCLogin begins a login at the named provider. It returns the URL to
+// send the browser to and the state value the callback must present.
+func (s *ServiceImpl) StartOIDCLogin(providerName string) (string, string, error) {
+	provider, ok := s.oidcProviders[providerName]
+	if !ok {
+		return "", "", domain.ErrUnknownProvider
+	}
+
+	state, err := randomToken()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := randomToken()
+	if err != nil {
+		return "", "", err
+	}
+	verifier, err := randomToken()
+	if err != nil {
+		return "", "", err
+	}
+
+	authURL, err := provider.AuthCodeURL(state, nonce, verifier)
+	if err != nil {
+		return "", "", fmt.Errorf("%w: %v", domain.ErrExternalLogin, err)
+	}
+	err = s.oidcStateRepo.Create(&domain.OIDCLoginState{
+		StateHash:    hashToken(state),
+		Provider:     providerName,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiresAt:    time.Now().Add(oidcLoginTTL),
+	})
+	if err != nil {
+		return "", "", err
+	}
+	return authURL, state, nil
+}
+
+// FinishOIDCLogin redeems the callback of StartOIDCLogin. The external identity
+// is linked to a User, and a new account is created on first login. Accounts
+// are never linked by matching email, since that would let any provider that
+// reports an address take over the local account using it.
+func (s *ServiceImpl) FinishOIDCLogin(providerName, state, code string) (*domain.User, error) {
+	provider, ok := s.oidcProviders[providerName]
+	if !ok {
+		return nil, domain.ErrUnknownProvider
+	}
+
+	pending, err := s.oidcStateRepo.Consume(hashToken(state))
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, domain.ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if pending.Provider != providerName || time.Now().After(pending.ExpiresAt) {
+		return nil, domain.ErrInvalidToken
+	}
+
+	external, err := provider.Exchange(code, pending.CodeVerifier, pending.Nonce)
+	if err != nil {
+		log.Printf("OIDC login with %s failed: %v", providerName, err)
+		return nil, domain.ErrExternalLogin
+	}
+
+	identity, err := s.identityRepo.FindByProviderSubject(providerName, external.Subject)
+	if err == nil {
+		return s.userRepo.FindByID(identity.UserID)
+	}
+	if !errors.Is(err, domain.ErrNotFound) {
+		return nil, err
+	}
+	return s.createExternalUser(providerName, external)
+}
+
+func (s *ServiceImpl) createExternalUser(providerName string, external *OIDCIdentity) (*domain.User, error) {
+	username, err := s.availableUsername(usernameCandidate(providerName, external))
+	if err != nil {
+		return nil, err
+	}
+	// The account has no usable password until the user sets one via password reset
+	secret, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	hashed, err := s.hasher.Hash(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &domain.User{Username: username, Password: hashed}
+	if external.Email != "" && external.EmailVerified {
+		if email, err := normalizeEmail(external.Email); err == nil {
+			if _, err := s.userRepo.FindByEmail(email); errors.Is(err, domain.ErrNotFound) {
+				now := time.Now()
+				user.Email = &email
+				user.VerifiedAt = &now
+			}
+		}
+	}
+
+	err = s.uow.Do(func(repos domain.Repositories) error {
+		if err := repos.Users.Create(user); err != nil {
+			return err
+		}
+		return repos.Identities.Create(&domain.Identity{
+			UserID:   user.ID,
+			Provider: providerName,
+			Subject:  external.Subject,
+			Email:    external.Email,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Created user %d (%s) from %s login", user.ID, user.Username, providerName)
+	return s.userRepo.FindByID(user.ID)
+}
+
+// usernameCandidate picks a readable username from the provider's claims.
+func usernameCandidate(providerName string, external *OIDCIdentity) string {
+	candidate := external.PreferredUsername
+	if candidate == "" && external.Email != "" {
+		candidate = strings.SplitN(external.Email, "@", 2)[0]
+	}
+	candidate = usernameUnsafe.ReplaceAllString(strings.ToLower(candidate), "")
+	if candidate == "" {
+		candidate = providerName + "-user"
+	}
+	return candidate
+}
+
+// availableUsername appends a number to base until no user has it.
+func (s *ServiceImpl) availableUsername(base string) (string, error) {
+	for i := 0; i < 100; i++ {
+		candidate := base
+		if i > 0 {
+			candidate = fmt.Sprintf("%s%d", base, i+1)
+		}
+		_, err := s.userRepo.FindByUsername(candidate)
+		if errors.Is(err, domain.ErrNotFound) {
+			return candidate, nil
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	return "", fmt.Errorf("no free username for %q", base)
+}
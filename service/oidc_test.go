@@ -0,0 +1,46 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestOIDCProviderLimitsJWKSRefetches(t *testing.T) {
+	var fetches atomic.Int32
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"issuer":"` + srv.URL + `","authorization_endpoint":"` + srv.URL + `/authorize",` +
+			`"token_endpoint":"` + srv.URL + `/token","jwks_uri":"` + srv.URL + `/jwks.json"}`))
+	})
+	mux.HandleFunc("GET /jwks.json", func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		w.Write([]byte(`{"keys":[{"kty":"OKP","crv":"Ed25519","kid":"known","use":"sig",` +
+			`"x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}]}`))
+	})
+
+	p := NewOIDCProvider(OIDCProviderConfig{Name: "test", Issuer: srv.URL, ClientID: "ecommerce"})
+	for i := 0; i < 5; i++ {
+		if _, err := p.key("forged"); err == nil {
+			t.Fatal("unknown kid was accepted")
+		}
+	}
+	if got := fetches.Load(); got != 1 {
+		t.Errorf("JWKS fetched %d times within the interval, want 1", got)
+	}
+	if _, err := p.key("known"); err != nil {
+		t.Errorf("known kid: %v", err)
+	}
+
+	p.mu.Lock()
+	p.keysFetchedAt = p.keysFetchedAt.Add(-jwksRefetchInterval - time.Second)
+	p.mu.Unlock()
+	p.key("rotated")
+	if got := fetches.Load(); got != 2 {
+		t.Errorf("JWKS fetched %d times after the interval, want 2", got)
+	}
+}
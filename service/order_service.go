@@ -0,0 +1,111 @@
+package service
+
+import (
+	"errors"
+	"log"
+
+	"ecommerce-api/domain"
+)
+
+func (s *ServiceImpl) GetOrders(userID uint) ([]domain.Order, error) {
+	return s.orderRepo.FindByUserID(userID)
+}
+
+// GetOrder returns an order only if it belongs to the given user.
+func (s *ServiceImpl) GetOrder(userID uint, orderID uint) (*domain.Order, error) {
+	order, err := s.orderRepo.FindByID(orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.UserID != userID {
+		return nil, domain.ErrNotFound // Don't reveal other users' orders
+	}
+	return order, nil
+}
+
+// HandlePaymentEvent applies a verified payment notification to its order.
+// Each provider event is applied at most once, so retried deliveries are harmless.
+//
+// A failed attempt leaves the order pending: the customer can still confirm the
+// same intent with another card. If they never do, the reservation sweeper
+// cancels the intent and only then fails the order and releases its stock.
+func (s *ServiceImpl) HandlePaymentEvent(event *domain.PaymentEvent) error {
+	var next domain.OrderStatus
+	switch event.Type {
+	case domain.PaymentEventSucceeded:
+		next = domain.OrderStatusPaid
+	case domain.PaymentEventFailed:
+		log.Printf("Payment attempt failed for intent %s (event %s); the order stays pending", event.PaymentIntentID, event.ID)
+		return nil
+	case domain.PaymentEventRefunded:
+		next = domain.OrderStatusRefunded
+	default:
+		return nil
+	}
+
+	return s.uow.Do(func(repos domain.Repositories) error {
+		fresh, err := repos.Events.MarkProcessed(event.ID, event.ProviderType)
+		if err != nil {
+			return err
+		}
+		if !fresh {
+			log.Printf("Skipping duplicate payment event %s", event.ID)
+			return nil
+		}
+
+		order, err := findOrderForEvent(repos.Orders, event)
+		if errors.Is(err, domain.ErrNotFound) {
+			log.Printf("No order found for payment event %s (intent %s)", event.ID, event.PaymentIntentID)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if !order.Status.CanTransitionTo(next) {
+			log.Printf("Ignoring payment event %s: order %d cannot move from %s to %s", event.ID, order.ID, order.Status, next)
+			return nil
+		}
+
+		if order.PaymentIntentID == "" {
+			order.PaymentIntentID = event.PaymentIntentID
+		}
+		return applyOrderStatus(repos, order, next)
+	})
+}
+
+// applyOrderStatus moves the order to next and settles its stock reservations:
+// a paid order takes the reserved stock for good, a failed one gives it back.
+func applyOrderStatus(repos domain.Repositories, order *domain.Order, next domain.OrderStatus) error {
+	switch next {
+	case domain.OrderStatusPaid:
+		if err := repos.Reservations.ConvertForOrder(order.ID); err != nil {
+			return err
+		}
+	case domain.OrderStatusFailed:
+		if err := repos.Reservations.ReleaseForOrder(order.ID); err != nil {
+			return err
+		}
+	}
+	order.Status = next
+	return repos.Orders.Update(order)
+}
+
+// findOrderForEvent prefers the order ID from the intent metadata, because the
+// webhook can arrive before Checkout has stored the intent ID on the order.
+func findOrderForEvent(orders domain.OrderRepository, event *domain.PaymentEvent) (*domain.Order, error) {
+	if event.OrderID != 0 {
+		order, err := orders.FindByID(event.OrderID)
+		if err != nil {
+			return nil, err
+		}
+		if order.PaymentIntentID != "" && order.PaymentIntentID != event.PaymentIntentID {
+			return nil, domain.ErrNotFound
+		}
+		return order, nil
+	}
+	if event.PaymentIntentID == "" {
+		return nil, domain.ErrNotFound
+	}
+	return orders.FindByPaymentIntentID(event.PaymentIntentID)
+}
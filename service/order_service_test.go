@@ -0,0 +1,84 @@
+package service
+
+import (
+	"testing"
+
+	"gorm.io/gorm"
+
+	"ecommerce-api/domain"
+)
+
+type seenEvents struct {
+	domain.ProcessedEventRepository
+	ids map[string]bool
+}
+
+func (r seenEvents) MarkProcessed(eventID string, eventType string) (bool, error) {
+	if r.ids[eventID] {
+		return false, nil
+	}
+	r.ids[eventID] = true
+	return true, nil
+}
+
+type oneOrder struct {
+	domain.OrderRepository
+	order *domain.Order
+}
+
+func (r oneOrder) FindByID(id uint) (*domain.Order, error) {
+	if id != r.order.ID {
+		return nil, domain.ErrNotFound
+	}
+	order := *r.order
+	return &order, nil
+}
+
+func (r oneOrder) Update(order *domain.Order) error {
+	*r.order = *order
+	return nil
+}
+
+// settledReservations records which orders had their stock taken or released.
+type settledReservations struct {
+	domain.ReservationRepository
+	converted, released []uint
+}
+
+func (r *settledReservations) ConvertForOrder(orderID uint) error {
+	r.converted = append(r.converted, orderID)
+	return nil
+}
+
+func (r *settledReservations) ReleaseForOrder(orderID uint) error {
+	r.released = append(r.released, orderID)
+	return nil
+}
+
+func TestHandlePaymentEventRetryAfterFailure(t *testing.T) {
+	order := &domain.Order{Model: gorm.Model{ID: 42}, Status: domain.OrderStatusPending, PaymentIntentID: "pi_1"}
+	reservations := &settledReservations{}
+	s := &ServiceImpl{uow: &recordingUnitOfWork{repos: domain.Repositories{
+		Events:       seenEvents{ids: map[string]bool{}},
+		Orders:       oneOrder{order: order},
+		Reservations: reservations,
+	}}}
+
+	// The first card is declined; the intent stays open at the provider
+	failed := &domain.PaymentEvent{ID: "evt_1", Type: domain.PaymentEventFailed, PaymentIntentID: "pi_1", OrderID: 42}
+	if err := s.HandlePaymentEvent(failed); err != nil {
+		t.Fatal(err)
+	}
+	if order.Status != domain.OrderStatusPending || len(reservations.released) != 0 {
+		t.Fatalf("a failed attempt settled the order: status %s, released %v", order.Status, reservations.released)
+	}
+
+	// The customer confirms the same intent with another card
+	succeeded := &domain.PaymentEvent{ID: "evt_2", Type: domain.PaymentEventSucceeded, PaymentIntentID: "pi_1", OrderID: 42}
+	if err := s.HandlePaymentEvent(succeeded); err != nil {
+		t.Fatal(err)
+	}
+	if order.Status != domain.OrderStatusPaid || len(reservations.converted) != 1 {
+		t.Errorf("got status %s, converted %v; want paid with its stock taken", order.Status, reservations.converted)
+	}
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"ecommerce-api/domain"
+)
+
+// --- Password reset ---
+
+// RequestPasswordReset sends a reset token to the user. It returns nil for
+// unknown usernames, and for known ones it returns before the token is stored
+// and sent, so neither the answer nor its timing reveals which accounts exist.
+// Failures after the lookup are only logged.
+func (s *ServiceImpl) RequestPasswordReset(username string) error {
+	user, err := s.userRepo.FindByUsername(username)
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	go s.sendPasswordReset(user)
+	return nil
+}
+
+// sendPasswordReset replaces the user's reset token with a new one and
+// delivers it.
+func (s *ServiceImpl) sendPasswordReset(user *domain.User) {
+	token, err := randomToken()
+	if err != nil {
+		log.Printf("Failed to create password reset token for user %d: %v", user.ID, err)
+		return
+	}
+	record := &domain.UserToken{
+		UserID:    user.ID,
+		Purpose:   domain.TokenPasswordReset,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(s.passwordResetTTL),
+	}
+	err = s.uow.Do(func(repos domain.Repositories) error {
+		// Only the most recent link works
+		if err := repos.UserTokens.InvalidateForUser(user.ID, domain.TokenPasswordReset); err != nil {
+			return err
+		}
+		return repos.UserTokens.Create(record)
+	})
+	if err != nil {
+		log.Printf("Failed to store password reset token for user %d: %v", user.ID, err)
+		return
+	}
+
+	if err := s.notifier.SendPasswordReset(user, token, record.ExpiresAt); err != nil {
+		log.Printf("Failed to deliver password reset for user %d: %v", user.ID, err)
+	}
+}
+
+// ResetPassword redeems a reset token, sets the new password and signs the
+// user out of every session.
+func (s *ServiceImpl) ResetPassword(token, newPassword string) error {
+	if newPassword == "" {
+		return domain.ErrInvalidPassword
+	}
+
+	stored, err := s.userTokenRepo.FindByHash(domain.TokenPasswordReset, hashToken(token))
+	if errors.Is(err, domain.ErrNotFound) {
+		return domain.ErrInvalidToken
+	}
+	if err != nil {
+		return err
+	}
+	if stored.UsedAt != nil || time.Now().After(stored.ExpiresAt) {
+		return domain.ErrInvalidToken
+	}
+
+	hashed, err := s.hasher.Hash(newPassword)
+	if err != nil {
+		return err
+	}
+
+	return s.uow.Do(func(repos domain.Repositories) error {
+		won, err := repos.UserTokens.MarkUsed(stored.ID)
+		if err != nil {
+			return err
+		}
+		if !won {
+			return domain.ErrInvalidToken
+		}
+		if err := repos.Users.UpdatePassword(stored.UserID, hashed); err != nil {
+			return err
+		}
+		return repos.RefreshTokens.RevokeAllForUser(stored.UserID)
+	})
+}
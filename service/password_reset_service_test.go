@@ -0,0 +1,82 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"ecommerce-api/domain"
+)
+
+type oneUser struct {
+	domain.UserRepository
+	user *domain.User
+}
+
+func (r oneUser) FindByUsername(username string) (*domain.User, error) {
+	if username != r.user.Username {
+		return nil, domain.ErrNotFound
+	}
+	return r.user, nil
+}
+
+type storedTokens struct {
+	domain.UserTokenRepository
+	tokens chan domain.UserToken
+}
+
+func (r storedTokens) InvalidateForUser(userID uint, purpose domain.TokenPurpose) error {
+	return nil
+}
+
+func (r storedTokens) Create(token *domain.UserToken) error {
+	r.tokens <- *token
+	return nil
+}
+
+// blockingNotifier holds each delivery until release is closed.
+type blockingNotifier struct {
+	release chan struct{}
+	sent    chan string
+}
+
+func (n blockingNotifier) SendPasswordReset(user *domain.User, token string, expiresAt time.Time) error {
+	<-n.release
+	n.sent <- token
+	return nil
+}
+
+func (n blockingNotifier) SendEmailVerification(user *domain.User, token string, expiresAt time.Time) error {
+	return nil
+}
+
+func TestRequestPasswordResetSendsInBackground(t *testing.T) {
+	tokens := storedTokens{tokens: make(chan domain.UserToken, 1)}
+	notifier := blockingNotifier{release: make(chan struct{}), sent: make(chan string, 1)}
+	s := &ServiceImpl{
+		userRepo:         oneUser{user: &domain.User{Model: gorm.Model{ID: 5}, Username: "alice"}},
+		uow:              &recordingUnitOfWork{repos: domain.Repositories{UserTokens: tokens}},
+		notifier:         notifier,
+		passwordResetTTL: time.Hour,
+	}
+
+	if err := s.RequestPasswordReset("nobody"); err != nil {
+		t.Fatalf("unknown username: %v", err)
+	}
+	// Delivery is still blocked, so this only returns if it does not wait for it
+	if err := s.RequestPasswordReset("alice"); err != nil {
+		t.Fatalf("known username: %v", err)
+	}
+	close(notifier.release)
+
+	select {
+	case token := <-notifier.sent:
+		stored := <-tokens.tokens
+		if stored.UserID != 5 || stored.TokenHash != hashToken(token) {
+			t.Errorf("stored %+v for the sent token", stored)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the reset was never sent")
+	}
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"ecommerce-api/domain"
+)
+
+// PaymentStatus mirrors the lifecycle states shared by the supported providers.
+type PaymentStatus string
+
+const (
+	PaymentStatusRequiresPayment PaymentStatus = "requires_payment_method"
+	PaymentStatusRequiresCapture PaymentStatus = "requires_capture"
+	PaymentStatusProcessing      PaymentStatus = "processing"
+	PaymentStatusSucceeded       PaymentStatus = "succeeded"
+	PaymentStatusCanceled        PaymentStatus = "canceled"
+)
+
+// PaymentIntent is the provider-neutral view of a payment.
+type PaymentIntent struct {
+	ID           string
+	ClientSecret string
+	Status       PaymentStatus
+	AmountCents  int64
+	Currency     string
+}
+
+// PaymentRefund is the provider-neutral view of a refund.
+type PaymentRefund struct {
+	ID          string
+	IntentID    string
+	AmountCents int64
+	Status      string
+}
+
+// PaymentIntentRequest describes the payment to start for an order.
+type PaymentIntentRequest struct {
+	AmountCents int64 // Smallest currency unit (e.g. cents)
+	Currency    string
+	Description string
+	OrderID     uint
+	// IdempotencyKey, if set, lets the provider deduplicate retried creations.
+	IdempotencyKey string
+}
+
+// PaymentGateway defines the contract for payment operations, independent of the provider.
+type PaymentGateway interface {
+	CreateIntent(req PaymentIntentRequest) (*PaymentIntent, error)
+	CaptureIntent(intentID string) (*PaymentIntent, error)
+	CancelIntent(intentID string) (*PaymentIntent, error)
+	RetrieveIntent(intentID string) (*PaymentIntent, error)
+	// Refund gives back amountCents of a captured payment; 0 refunds whatever is left.
+	Refund(intentID string, amountCents int64) (*PaymentRefund, error)
+}
+
+// WebhookVerifier authenticates provider webhooks and translates them into domain events.
+type WebhookVerifier interface {
+	ConstructWebhookEvent(payload []byte, signature string) (*domain.PaymentEvent, error)
+}
@@ -0,0 +1,237 @@
+package service
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+
+	"ecommerce-api/domain"
+)
+
+const (
+	maxImagesPerProduct = 20
+	maxAltTextLength    = 500
+	// maxImagePixels bounds the memory needed to decode an upload, which a
+	// small but highly compressed file could otherwise blow up.
+	maxImagePixels = 40_000_000
+)
+
+// imageExtensions lists the accepted upload types by their sniffed content type.
+var imageExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+// thumbnailSizes are the longest sides of the generated thumbnails, from
+// largest to smallest: each is scaled down from the one before.
+var thumbnailSizes = []struct {
+	name    string
+	maxSide int
+}{
+	{"medium", 800},
+	{"small", 200},
+}
+
+// blob is a file waiting to be written to the blob store.
+type blob struct {
+	key         string
+	contentType string
+	data        []byte
+}
+
+// --- Product images ---
+
+// UploadProductImage validates an uploaded image, stores it with its
+// thumbnails and appends it to the product's images. declaredType is the
+// content type the client sent; the type is taken from the content itself.
+func (s *ServiceImpl) UploadProductImage(productID uint, content []byte, declaredType, altText string) (*domain.ProductImage, error) {
+	if int64(len(content)) > s.maxImageBytes {
+		return nil, fmt.Errorf("%w: the limit is %d bytes", domain.ErrImageTooLarge, s.maxImageBytes)
+	}
+	detected := http.DetectContentType(content)
+	ext, ok := imageExtensions[detected]
+	if !ok {
+		return nil, domain.ErrUnsupportedImage
+	}
+	if declared, _, err := mime.ParseMediaType(declaredType); err == nil && declared != "application/octet-stream" && declared != detected {
+		return nil, fmt.Errorf("%w: sent as %s but the content is %s", domain.ErrInvalidImage, declared, detected)
+	}
+	altText = strings.TrimSpace(altText)
+	if len(altText) > maxAltTextLength {
+		return nil, fmt.Errorf("%w: alt text must be at most %d characters", domain.ErrInvalidImage, maxAltTextLength)
+	}
+
+	product, err := s.productRepo.FindByID(productID)
+	if err != nil {
+		return nil, err
+	}
+	if len(product.Images) >= maxImagesPerProduct {
+		return nil, domain.ErrTooManyImages
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("%w: the file could not be decoded", domain.ErrInvalidImage)
+	}
+	if config.Width <= 0 || config.Height <= 0 {
+		return nil, fmt.Errorf("%w: the image has no pixels", domain.ErrInvalidImage)
+	}
+	if config.Width*config.Height > maxImagePixels {
+		return nil, fmt.Errorf("%w: %dx%d pixels exceeds the limit of %d", domain.ErrImageTooLarge, config.Width, config.Height, maxImagePixels)
+	}
+	decoded, _, err := image.Decode(bytes.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("%w: the file could not be decoded", domain.ErrInvalidImage)
+	}
+
+	// A random directory per upload keeps URLs unguessable and cache-safe
+	random, err := randomBytes(8)
+	if err != nil {
+		return nil, err
+	}
+	prefix := fmt.Sprintf("products/%d/%s/", productID, hex.EncodeToString(random))
+	img := &domain.ProductImage{
+		ProductID:   productID,
+		Position:    len(product.Images),
+		AltText:     altText,
+		ContentType: detected,
+		SizeBytes:   int64(len(content)),
+		Width:       config.Width,
+		Height:      config.Height,
+		Key:         prefix + "original" + ext,
+	}
+	blobs := []blob{{key: img.Key, contentType: detected, data: content}}
+	thumb := toRGBA(decoded)
+	for _, size := range thumbnailSizes {
+		thumb = resizeToFit(thumb, size.maxSide)
+		data, contentType, thumbExt, err := encodeThumbnail(thumb)
+		if err != nil {
+			return nil, err
+		}
+		key := prefix + size.name + thumbExt
+		blobs = append(blobs, blob{key: key, contentType: contentType, data: data})
+		switch size.name {
+		case "small":
+			img.SmallKey = key
+		case "medium":
+			img.MediumKey = key
+		}
+	}
+
+	for i, b := range blobs {
+		if err := s.blobs.Put(b.key, b.contentType, b.data); err != nil {
+			s.deleteBlobs(blobKeys(blobs[:i])...)
+			return nil, fmt.Errorf("storing image: %w", err)
+		}
+	}
+	if err := s.productImageRepo.Create(img); err != nil {
+		s.deleteBlobs(blobKeys(blobs)...)
+		return nil, err
+	}
+	return img, nil
+}
+
+// UpdateProductImage changes an image's alt text, or moves it to position
+// within the product's images. It returns the product with its images.
+func (s *ServiceImpl) UpdateProductImage(productID uint, imageID uint, altText *string, position *int) (*domain.Product, error) {
+	product, err := s.productRepo.FindByID(productID)
+	if err != nil {
+		return nil, err
+	}
+	index := imageIndex(product.Images, imageID)
+	if index < 0 {
+		return nil, domain.ErrNotFound
+	}
+
+	if altText != nil {
+		trimmed := strings.TrimSpace(*altText)
+		if len(trimmed) > maxAltTextLength {
+			return nil, fmt.Errorf("%w: alt text must be at most %d characters", domain.ErrInvalidImage, maxAltTextLength)
+		}
+		product.Images[index].AltText = trimmed
+		if err := s.productImageRepo.UpdateAltText(&product.Images[index]); err != nil {
+			return nil, err
+		}
+	}
+
+	if position != nil {
+		if *position < 0 || *position >= len(product.Images) {
+			return nil, fmt.Errorf("%w: position must be between 0 and %d", domain.ErrInvalidImage, len(product.Images)-1)
+		}
+		moved := product.Images[index]
+		images := append(product.Images[:index:index], product.Images[index+1:]...)
+		images = append(images[:*position], append([]domain.ProductImage{moved}, images[*position:]...)...)
+		if err := s.setImagePositions(productID, images); err != nil {
+			return nil, err
+		}
+		product.Images = images
+	}
+	return product, nil
+}
+
+// DeleteProductImage removes the image and its files, and closes the gap in
+// the product's image order.
+func (s *ServiceImpl) DeleteProductImage(productID uint, imageID uint) error {
+	images, err := s.productImageRepo.FindByProductID(productID)
+	if err != nil {
+		return err
+	}
+	index := imageIndex(images, imageID)
+	if index < 0 {
+		return domain.ErrNotFound
+	}
+	img := images[index]
+	if err := s.productImageRepo.Delete(img.ID); err != nil {
+		return err
+	}
+	s.deleteBlobs(img.Key, img.SmallKey, img.MediumKey)
+
+	remaining := append(images[:index:index], images[index+1:]...)
+	return s.setImagePositions(productID, remaining)
+}
+
+func (s *ServiceImpl) setImagePositions(productID uint, images []domain.ProductImage) error {
+	ids := make([]uint, len(images))
+	for i := range images {
+		ids[i] = images[i].ID
+		images[i].Position = i
+	}
+	return s.productImageRepo.SetPositions(productID, ids)
+}
+
+// deleteBlobs removes files best-effort; a leftover file is only wasted space.
+func (s *ServiceImpl) deleteBlobs(keys ...string) {
+	for _, key := range keys {
+		if err := s.blobs.Delete(key); err != nil {
+			log.Printf("Warning: could not delete blob %s: %v", key, err)
+		}
+	}
+}
+
+// ImageURL returns where clients can download the blob with the given key.
+func (s *ServiceImpl) ImageURL(key string) string {
+	return s.blobs.URL(key)
+}
+
+func blobKeys(blobs []blob) []string {
+	keys := make([]string, len(blobs))
+	for i, b := range blobs {
+		keys[i] = b.key
+	}
+	return keys
+}
+
+func imageIndex(images []domain.ProductImage, id uint) int {
+	for i := range images {
+		if images[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
@@ -1,13 +1,223 @@
 package service
 
 import (
+	"errors"
+	"fmt"
+	"strings"
+
 	"ecommerce-api/domain"
 )
 
+// CreateProduct stores the product with its variants and books each variant's
+// initial stock as a receiving movement. A product given without variants is
+// sold through a single default variant that holds product.Inventory. Its SKU
+// is "P<product id>", or "P<product id>-2" and so on if a variant already uses that.
 func (s *ServiceImpl) CreateProduct(product *domain.Product) error {
-	return s.productRepo.Create(product)
+	if err := validateProduct(product); err != nil {
+		return err
+	}
+	variants := product.Variants
+	if len(variants) == 0 {
+		variants = []domain.ProductVariant{{Inventory: product.Inventory}}
+	} else {
+		if product.Inventory != 0 {
+			return fmt.Errorf("%w: inventory is set per variant", domain.ErrInvalidProduct)
+		}
+		for i := range variants {
+			if err := s.prepareVariant(&variants[i], variants[:i]); err != nil {
+				return err
+			}
+		}
+	}
+	product.Variants = nil
+	product.Inventory = 0
+	product.Reserved = 0 // Only checkout may hold stock
+	product.Version = 1
+
+	err := s.uow.Do(func(repos domain.Repositories) error {
+		if err := repos.Products.Create(product); err != nil {
+			return err
+		}
+		for i := range variants {
+			if variants[i].SKU == "" {
+				sku, err := defaultSKU(repos, product.ID)
+				if err != nil {
+					return err
+				}
+				variants[i].SKU = sku
+			}
+			if err := createVariant(repos, product.ID, &variants[i], "Initial stock"); err != nil {
+				return err
+			}
+			product.Inventory += variants[i].Inventory
+		}
+		return nil
+	})
+	product.Variants = variants
+	return err
+}
+
+// defaultSKU picks the first free SKU of the form "P<id>", "P<id>-2", ... for
+// a product's default variant. Other variants may already have chosen "P<id>".
+func defaultSKU(repos domain.Repositories, productID uint) (string, error) {
+	base := fmt.Sprintf("P%d", productID)
+	for i := 1; i <= 100; i++ {
+		sku := base
+		if i > 1 {
+			sku = fmt.Sprintf("%s-%d", base, i)
+		}
+		_, err := repos.Variants.FindBySKU(sku)
+		if errors.Is(err, domain.ErrNotFound) {
+			return sku, nil
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	return "", fmt.Errorf("%w: no free default SKU for product %d", domain.ErrSKUTaken, productID)
 }
 
-func (s *ServiceImpl) GetProducts(query string) ([]domain.Product, error) {
+// Page sizes of product listings.
+const (
+	defaultProductPageSize = 20
+	maxProductPageSize     = 100
+)
+
+// Longer search strings only make the query more expensive to parse and rank.
+const maxSearchLength = 200
+
+// GetProducts returns a page of the catalog. An unset sort lists the best
+// search matches first, or the newest products when there is no search.
+func (s *ServiceImpl) GetProducts(query domain.ProductQuery) (*domain.ProductPage, error) {
+	query.Search = strings.TrimSpace(query.Search)
+	if len(query.Search) > maxSearchLength {
+		return nil, fmt.Errorf("%w: search must be at most %d characters", domain.ErrInvalidQuery, maxSearchLength)
+	}
+	if query.Sort == "" {
+		query.Sort = domain.SortNewest
+		if query.Search != "" {
+			query.Sort = domain.SortRelevance
+		}
+	}
+	if !query.Sort.Valid() {
+		return nil, fmt.Errorf("%w: unknown sort %q", domain.ErrInvalidQuery, query.Sort)
+	}
+	if query.Sort == domain.SortRelevance && query.Search == "" {
+		return nil, fmt.Errorf("%w: sorting by relevance needs a search", domain.ErrInvalidQuery)
+	}
+	switch {
+	case query.Limit == 0:
+		query.Limit = defaultProductPageSize
+	case query.Limit < 0 || query.Limit > maxProductPageSize:
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", domain.ErrInvalidQuery, maxProductPageSize)
+	}
+	if query.MinPriceCents != nil && query.MaxPriceCents != nil && *query.MinPriceCents > *query.MaxPriceCents {
+		return nil, fmt.Errorf("%w: min_price_cents is above max_price_cents", domain.ErrInvalidQuery)
+	}
 	return s.productRepo.FindAll(query)
 }
+
+func (s *ServiceImpl) GetProduct(id uint) (*domain.Product, error) {
+	return s.productRepo.FindByID(id)
+}
+
+// UpdateProduct applies changes to the product if it is still at version, so an
+// edit based on a stale copy fails with ErrVersionConflict instead of silently
+// overwriting someone else's. Stock is changed through AdjustInventory only.
+func (s *ServiceImpl) UpdateProduct(id uint, version int, changes domain.ProductChanges) (*domain.Product, error) {
+	product, err := s.productRepo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if product.Version != version {
+		return nil, domain.ErrVersionConflict
+	}
+
+	if changes.Name != nil {
+		product.Name = *changes.Name
+	}
+	if changes.Description != nil {
+		product.Description = *changes.Description
+	}
+	if changes.PriceCents != nil {
+		product.PriceCents = *changes.PriceCents
+	}
+	if err := validateProduct(product); err != nil {
+		return nil, err
+	}
+
+	if err := s.productRepo.Update(product); err != nil {
+		return nil, err
+	}
+	return product, nil
+}
+
+// DeleteProduct hides the product from the catalog and carts. Its row, ledger
+// and order history are kept, and pending checkouts still settle.
+func (s *ServiceImpl) DeleteProduct(id uint) error {
+	return s.productRepo.Delete(id)
+}
+
+func (s *ServiceImpl) RestoreProduct(id uint) (*domain.Product, error) {
+	if err := s.productRepo.Restore(id); err != nil {
+		return nil, err
+	}
+	return s.productRepo.FindByID(id)
+}
+
+func validateProduct(product *domain.Product) error {
+	product.Name = strings.TrimSpace(product.Name)
+	switch {
+	case product.Name == "":
+		return fmt.Errorf("%w: name is required", domain.ErrInvalidProduct)
+	case product.PriceCents <= 0:
+		return fmt.Errorf("%w: price must be positive", domain.ErrInvalidProduct)
+	case product.Inventory < 0:
+		return fmt.Errorf("%w: inventory must not be negative", domain.ErrInvalidProduct)
+	}
+	return nil
+}
+
+// AdjustInventory records a manual stock change made by staff. variantID may
+// be zero for a product with a single variant.
+func (s *ServiceImpl) AdjustInventory(actorID uint, productID uint, variantID uint, quantity int, reason domain.MovementReason, note string) (*domain.InventoryMovement, error) {
+	if !reason.IsManual() {
+		return nil, fmt.Errorf("%w: reason must be %q or %q", domain.ErrInvalidAdjustment, domain.MovementAdjustment, domain.MovementReceiving)
+	}
+	if quantity == 0 {
+		return nil, fmt.Errorf("%w: quantity must not be zero", domain.ErrInvalidAdjustment)
+	}
+	if reason == domain.MovementReceiving && quantity < 0 {
+		return nil, fmt.Errorf("%w: received quantity must be positive", domain.ErrInvalidAdjustment)
+	}
+
+	product, err := s.productRepo.FindByID(productID)
+	if err != nil {
+		return nil, err
+	}
+	variant, err := chooseVariant(product, variantID)
+	if err != nil {
+		return nil, err
+	}
+
+	movement := &domain.InventoryMovement{
+		VariantID:      variant.ID,
+		Reason:         reason,
+		InventoryDelta: quantity,
+		Reference:      fmt.Sprintf("user:%d", actorID),
+		Note:           note,
+		ActorID:        &actorID,
+	}
+	if err := s.inventoryRepo.Record(movement); err != nil {
+		return nil, err
+	}
+	return movement, nil
+}
+
+// GetInventoryHistory returns the most recent ledger entries for a product, newest first.
+func (s *ServiceImpl) GetInventoryHistory(productID uint, limit int) ([]domain.InventoryMovement, error) {
+	if _, err := s.productRepo.FindByID(productID); err != nil {
+		return nil, err
+	}
+	return s.inventoryRepo.FindByProductID(productID, limit)
+}
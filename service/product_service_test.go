@@ -0,0 +1,42 @@
+package service
+
+import (
+	"testing"
+
+	"ecommerce-api/domain"
+)
+
+// takenSKUs is a VariantRepository whose FindBySKU knows a fixed set of SKUs.
+type takenSKUs struct {
+	domain.VariantRepository
+	skus map[string]bool
+}
+
+func (r takenSKUs) FindBySKU(sku string) (*domain.ProductVariant, error) {
+	if r.skus[sku] {
+		return &domain.ProductVariant{SKU: sku}, nil
+	}
+	return nil, domain.ErrNotFound
+}
+
+func TestDefaultSKU(t *testing.T) {
+	tests := []struct {
+		taken []string
+		want  string
+	}{
+		{nil, "P17"},
+		{[]string{"P1", "P170"}, "P17"},
+		{[]string{"P17"}, "P17-2"},
+		{[]string{"P17", "P17-2", "P17-3"}, "P17-4"},
+	}
+	for _, tt := range tests {
+		repo := takenSKUs{skus: map[string]bool{}}
+		for _, sku := range tt.taken {
+			repo.skus[sku] = true
+		}
+		got, err := defaultSKU(domain.Repositories{Variants: repo}, 17)
+		if err != nil || got != tt.want {
+			t.Errorf("taken %v: got %q, %v; want %q", tt.taken, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,390 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"ecommerce-api/domain"
+)
+
+// Bulk import limits. Rows are read into memory before the transaction starts,
+// so a slow upload never holds it open.
+const (
+	importBatchSize = 500
+	maxImportRows   = 50000
+	maxImportErrors = 100 // Row errors reported; the rest are only counted
+	exportBatchSize = 500
+)
+
+// errImportRolledBack ends an import transaction without saving it.
+var errImportRolledBack = errors.New("import rolled back")
+
+// ImportProducts upserts variants by SKU from rows, with the same rules as
+// creating products and variants one by one. An existing SKU updates its
+// product's catalog fields, its price override and, when given, its stock; a
+// new SKU is added to ProductID, or else to a product created for it. New SKUs
+// without a ProductID that share a name become variants of one new product.
+//
+// All rows are applied in one transaction, in batches. If any row fails, or in
+// a dry run, the transaction is rolled back and the result lists the row errors.
+func (s *ServiceImpl) ImportProducts(actorID uint, rows domain.ProductRowReader, dryRun bool) (*domain.ImportResult, error) {
+	result := &domain.ImportResult{DryRun: dryRun}
+	var parsed []domain.ProductRow
+	for {
+		row, err := rows.Next()
+		if err == io.EOF {
+			break
+		}
+		var rowErr *domain.RowError
+		switch {
+		case errors.As(err, &rowErr):
+			result.Rows++
+			addRowError(result, *rowErr)
+		case err != nil:
+			return nil, err
+		default:
+			result.Rows++
+			parsed = append(parsed, *row)
+		}
+		if result.Rows > maxImportRows {
+			return nil, fmt.Errorf("%w: at most %d rows per import", domain.ErrInvalidImport, maxImportRows)
+		}
+	}
+	if result.Rows == 0 {
+		return nil, fmt.Errorf("%w: no rows", domain.ErrInvalidImport)
+	}
+
+	optionTypes, err := s.optionTypeRepo.FindAll()
+	if err != nil {
+		return nil, err
+	}
+	err = s.uow.Do(func(repos domain.Repositories) error {
+		imp := &productImporter{
+			repos:       repos,
+			actorID:     actorID,
+			optionTypes: optionTypes,
+			result:      result,
+			seenSKUs:    make(map[string]bool),
+			products:    make(map[uint]*domain.Product),
+			variants:    make(map[uint][]domain.ProductVariant),
+			newProducts: make(map[string]uint),
+		}
+		for start := 0; start < len(parsed); start += importBatchSize {
+			if err := imp.importBatch(parsed[start:min(start+importBatchSize, len(parsed))]); err != nil {
+				return err
+			}
+		}
+		if dryRun || result.ErrorCount > 0 {
+			return errImportRolledBack
+		}
+		return nil
+	})
+	// Parse errors were found before the rest, so put them back in file order.
+	sort.SliceStable(result.Errors, func(i, j int) bool { return result.Errors[i].Line < result.Errors[j].Line })
+	if errors.Is(err, errImportRolledBack) {
+		return result, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	result.Committed = true
+	return result, nil
+}
+
+// ExportProducts passes every variant of the live catalog to fn as a row, in
+// variant ID order, loading them in batches. It stops at the first error of fn.
+func (s *ServiceImpl) ExportProducts(fn func(row *domain.ProductRow) error) error {
+	var afterID uint
+	for {
+		variants, err := s.variantRepo.FindAfter(afterID, exportBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(variants) == 0 {
+			return nil
+		}
+		ids := make([]uint, 0, len(variants))
+		for _, v := range variants {
+			ids = append(ids, v.ProductID)
+		}
+		products, err := s.productRepo.FindByIDs(ids)
+		if err != nil {
+			return err
+		}
+		byID := make(map[uint]*domain.Product, len(products))
+		for i := range products {
+			byID[products[i].ID] = &products[i]
+		}
+
+		for i := range variants {
+			variant := &variants[i]
+			product := byID[variant.ProductID]
+			if product == nil {
+				continue // Deleted since the variants were read
+			}
+			inventory := variant.Inventory
+			err := fn(&domain.ProductRow{
+				SKU:               variant.SKU,
+				ProductID:         product.ID,
+				Name:              product.Name,
+				Description:       product.Description,
+				PriceCents:        product.PriceCents,
+				VariantPriceCents: variant.PriceCents,
+				Inventory:         &inventory,
+				Options:           variant.OptionMap(),
+			})
+			if err != nil {
+				return err
+			}
+		}
+		afterID = variants[len(variants)-1].ID
+	}
+}
+
+// productImporter applies import rows within one transaction and remembers
+// what earlier rows loaded or created.
+type productImporter struct {
+	repos       domain.Repositories
+	actorID     uint
+	optionTypes []domain.OptionType
+	result      *domain.ImportResult
+
+	seenSKUs    map[string]bool
+	products    map[uint]*domain.Product
+	variants    map[uint][]domain.ProductVariant // By product, loaded when a SKU is added to it
+	newProducts map[string]uint                  // Products created by this import, by name
+}
+
+// importBatch looks up the batch's SKUs and products together, then applies
+// the rows one by one. Row errors are recorded; other errors abort the import.
+func (imp *productImporter) importBatch(rows []domain.ProductRow) error {
+	skus := make([]string, 0, len(rows))
+	for i := range rows {
+		rows[i].SKU = strings.TrimSpace(rows[i].SKU)
+		skus = append(skus, rows[i].SKU)
+	}
+	existing, err := imp.repos.Variants.FindBySKUs(skus)
+	if err != nil {
+		return err
+	}
+	bySKU := make(map[string]*domain.ProductVariant, len(existing))
+	var productIDs []uint
+	for i := range existing {
+		bySKU[existing[i].SKU] = &existing[i]
+		productIDs = append(productIDs, existing[i].ProductID)
+	}
+	for _, row := range rows {
+		if row.ProductID != 0 {
+			productIDs = append(productIDs, row.ProductID)
+		}
+	}
+	if err := imp.loadProducts(productIDs); err != nil {
+		return err
+	}
+
+	for i := range rows {
+		row := &rows[i]
+		err := imp.importRow(row, bySKU[row.SKU])
+		if err != nil && !isRowError(err) {
+			return err
+		}
+		if err != nil {
+			addRowError(imp.result, domain.RowError{Line: row.Line, SKU: row.SKU, Message: err.Error()})
+		}
+	}
+	return nil
+}
+
+func (imp *productImporter) loadProducts(ids []uint) error {
+	var missing []uint
+	for _, id := range ids {
+		if _, ok := imp.products[id]; !ok {
+			missing = append(missing, id)
+		}
+	}
+	products, err := imp.repos.Products.FindByIDs(missing)
+	if err != nil {
+		return err
+	}
+	for i := range products {
+		imp.products[products[i].ID] = &products[i]
+	}
+	return nil
+}
+
+// importRow validates the row completely before writing anything for it.
+func (imp *productImporter) importRow(row *domain.ProductRow, existing *domain.ProductVariant) error {
+	if imp.seenSKUs[row.SKU] {
+		return fmt.Errorf("%w: SKU %q appears more than once in the file", domain.ErrInvalidImport, row.SKU)
+	}
+	imp.seenSKUs[row.SKU] = true
+
+	fields := domain.Product{Name: row.Name, Description: row.Description, PriceCents: row.PriceCents}
+	if err := validateProduct(&fields); err != nil {
+		return err
+	}
+	if existing != nil {
+		return imp.updateVariant(row, existing, &fields)
+	}
+	return imp.createVariant(row, &fields)
+}
+
+func (imp *productImporter) updateVariant(row *domain.ProductRow, variant *domain.ProductVariant, fields *domain.Product) error {
+	product := imp.products[variant.ProductID]
+	switch {
+	case product == nil:
+		return fmt.Errorf("%w: it belongs to a deleted product", domain.ErrSKUTaken)
+	case row.ProductID != 0 && row.ProductID != product.ID:
+		return fmt.Errorf("%w: it belongs to product %d", domain.ErrSKUTaken, product.ID)
+	case row.VariantPriceCents != nil && *row.VariantPriceCents <= 0:
+		return fmt.Errorf("%w: price must be positive", domain.ErrInvalidVariant)
+	case row.Inventory != nil && *row.Inventory < 0:
+		return fmt.Errorf("%w: inventory must not be negative", domain.ErrInvalidVariant)
+	case row.Inventory != nil && *row.Inventory < variant.Reserved:
+		return fmt.Errorf("%w: %d units are reserved", domain.ErrInsufficientInv, variant.Reserved)
+	}
+	if len(row.Options) > 0 {
+		current := variant.OptionMap()
+		same := len(row.Options) == len(current)
+		for name, value := range row.Options {
+			if current[strings.ToLower(strings.TrimSpace(name))] != strings.TrimSpace(value) {
+				same = false
+			}
+		}
+		if !same {
+			return fmt.Errorf("%w: options of an existing variant cannot change", domain.ErrInvalidVariant)
+		}
+	}
+
+	if err := imp.updateProduct(product, fields); err != nil {
+		return err
+	}
+	if !equalPrice(variant.PriceCents, row.VariantPriceCents) {
+		variant.PriceCents = row.VariantPriceCents
+		if err := imp.repos.Variants.Update(variant); err != nil {
+			return err
+		}
+	}
+	if row.Inventory != nil && *row.Inventory != variant.Inventory {
+		err := imp.repos.Inventory.Record(&domain.InventoryMovement{
+			VariantID:      variant.ID,
+			Reason:         domain.MovementAdjustment,
+			InventoryDelta: *row.Inventory - variant.Inventory,
+			Reference:      fmt.Sprintf("user:%d", imp.actorID),
+			Note:           "Bulk import",
+			ActorID:        &imp.actorID,
+		})
+		if err != nil {
+			return err
+		}
+		variant.Inventory = *row.Inventory
+	}
+	imp.result.UpdatedVariants++
+	return nil
+}
+
+func (imp *productImporter) createVariant(row *domain.ProductRow, fields *domain.Product) error {
+	variant := domain.ProductVariant{SKU: row.SKU, PriceCents: row.VariantPriceCents}
+	if row.Inventory != nil {
+		variant.Inventory = *row.Inventory
+	}
+	for name, value := range row.Options {
+		variant.Options = append(variant.Options, domain.VariantOption{OptionType: domain.OptionType{Name: name}, Value: value})
+	}
+	if _, err := checkVariant(&variant); err != nil {
+		return err
+	}
+
+	var product *domain.Product
+	if row.ProductID != 0 {
+		if product = imp.products[row.ProductID]; product == nil {
+			return fmt.Errorf("%w: product %d", domain.ErrNotFound, row.ProductID)
+		}
+	} else if id, ok := imp.newProducts[fields.Name]; ok {
+		product = imp.products[id]
+	}
+	var siblings []domain.ProductVariant
+	if product != nil {
+		var err error
+		if siblings, err = imp.siblings(product.ID); err != nil {
+			return err
+		}
+	}
+	if err := resolveOptions(&variant, siblings, imp.optionTypes); err != nil {
+		return err
+	}
+
+	if product == nil {
+		product = &domain.Product{Name: fields.Name, Description: fields.Description, PriceCents: fields.PriceCents, Version: 1}
+		if err := imp.repos.Products.Create(product); err != nil {
+			return err
+		}
+		imp.products[product.ID] = product
+		imp.variants[product.ID] = nil
+		imp.newProducts[product.Name] = product.ID
+		imp.result.CreatedProducts++
+	} else if err := imp.updateProduct(product, fields); err != nil {
+		return err
+	}
+	if err := createVariant(imp.repos, product.ID, &variant, "Bulk import"); err != nil {
+		return err
+	}
+	imp.variants[product.ID] = append(imp.variants[product.ID], variant)
+	imp.result.CreatedVariants++
+	return nil
+}
+
+// siblings returns the product's variants, including those added by this import.
+func (imp *productImporter) siblings(productID uint) ([]domain.ProductVariant, error) {
+	if variants, ok := imp.variants[productID]; ok {
+		return variants, nil
+	}
+	variants, err := imp.repos.Variants.FindByProductID(productID)
+	if err != nil {
+		return nil, err
+	}
+	imp.variants[productID] = variants
+	return variants, nil
+}
+
+// updateProduct writes the row's catalog fields if they differ, so rows that
+// repeat a product's fields for each variant change it only once.
+func (imp *productImporter) updateProduct(product *domain.Product, fields *domain.Product) error {
+	if product.Name == fields.Name && product.Description == fields.Description && product.PriceCents == fields.PriceCents {
+		return nil
+	}
+	product.Name = fields.Name
+	product.Description = fields.Description
+	product.PriceCents = fields.PriceCents
+	return imp.repos.Products.Update(product)
+}
+
+func equalPrice(a, b *int64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// isRowError reports whether err rejects a single row rather than the import.
+func isRowError(err error) bool {
+	for _, target := range []error{
+		domain.ErrInvalidImport, domain.ErrInvalidProduct, domain.ErrInvalidVariant, domain.ErrSKUTaken,
+		domain.ErrNotFound, domain.ErrVersionConflict, domain.ErrInsufficientInv,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func addRowError(result *domain.ImportResult, rowErr domain.RowError) {
+	result.ErrorCount++
+	if len(result.Errors) < maxImportErrors {
+		result.Errors = append(result.Errors, rowErr)
+	}
+}
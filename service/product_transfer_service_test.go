@@ -0,0 +1,247 @@
+package service
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+
+	"gorm.io/gorm"
+
+	"ecommerce-api/domain"
+)
+
+// memoryCatalog backs the product, variant and inventory fakes of an import.
+type memoryCatalog struct {
+	products  map[uint]*domain.Product
+	variants  []domain.ProductVariant
+	movements []domain.InventoryMovement
+	nextID    uint
+}
+
+func newMemoryCatalog() *memoryCatalog {
+	return &memoryCatalog{
+		products: map[uint]*domain.Product{1: {Model: gorm.Model{ID: 1}, Name: "Mug", PriceCents: 899, Version: 1}},
+		variants: []domain.ProductVariant{{Model: gorm.Model{ID: 10}, ProductID: 1, SKU: "MUG", Inventory: 5}},
+		nextID:   100,
+	}
+}
+
+func (c *memoryCatalog) repositories() domain.Repositories {
+	return domain.Repositories{
+		Products:  catalogProducts{c: c},
+		Variants:  catalogVariants{c: c},
+		Inventory: catalogInventory{c: c},
+	}
+}
+
+type catalogProducts struct {
+	domain.ProductRepository
+	c *memoryCatalog
+}
+
+func (r catalogProducts) Create(product *domain.Product) error {
+	r.c.nextID++
+	product.ID = r.c.nextID
+	stored := *product
+	r.c.products[product.ID] = &stored
+	return nil
+}
+
+func (r catalogProducts) FindByIDs(ids []uint) ([]domain.Product, error) {
+	var products []domain.Product
+	for _, id := range ids {
+		if p, ok := r.c.products[id]; ok {
+			products = append(products, *p)
+		}
+	}
+	return products, nil
+}
+
+func (r catalogProducts) Update(product *domain.Product) error {
+	stored := r.c.products[product.ID]
+	if stored == nil || stored.Version != product.Version {
+		return domain.ErrVersionConflict
+	}
+	product.Version++
+	*stored = *product
+	return nil
+}
+
+type catalogVariants struct {
+	domain.VariantRepository
+	c *memoryCatalog
+}
+
+func (r catalogVariants) Create(variant *domain.ProductVariant) error {
+	r.c.nextID++
+	variant.ID = r.c.nextID
+	r.c.variants = append(r.c.variants, *variant)
+	return nil
+}
+
+func (r catalogVariants) FindBySKUs(skus []string) ([]domain.ProductVariant, error) {
+	var found []domain.ProductVariant
+	for _, v := range r.c.variants {
+		for _, sku := range skus {
+			if v.SKU == sku {
+				found = append(found, v)
+				break
+			}
+		}
+	}
+	return found, nil
+}
+
+func (r catalogVariants) FindByProductID(productID uint) ([]domain.ProductVariant, error) {
+	var found []domain.ProductVariant
+	for _, v := range r.c.variants {
+		if v.ProductID == productID {
+			found = append(found, v)
+		}
+	}
+	return found, nil
+}
+
+func (r catalogVariants) Update(variant *domain.ProductVariant) error {
+	for i := range r.c.variants {
+		if r.c.variants[i].ID == variant.ID {
+			r.c.variants[i].PriceCents = variant.PriceCents
+			return nil
+		}
+	}
+	return domain.ErrNotFound
+}
+
+type catalogInventory struct {
+	domain.InventoryRepository
+	c *memoryCatalog
+}
+
+func (r catalogInventory) Record(movement *domain.InventoryMovement) error {
+	for i := range r.c.variants {
+		if r.c.variants[i].ID == movement.VariantID {
+			r.c.variants[i].Inventory += movement.InventoryDelta
+			r.c.movements = append(r.c.movements, *movement)
+			return nil
+		}
+	}
+	return domain.ErrNotFound
+}
+
+// recordingUnitOfWork runs fn against fixed repositories and keeps the error
+// it ended with; it cannot roll anything back.
+type recordingUnitOfWork struct {
+	repos domain.Repositories
+	err   error
+}
+
+func (u *recordingUnitOfWork) Do(fn func(repos domain.Repositories) error) error {
+	u.err = fn(u.repos)
+	return u.err
+}
+
+type knownOptionTypes struct {
+	domain.OptionTypeRepository
+	types []domain.OptionType
+}
+
+func (r knownOptionTypes) FindAll() ([]domain.OptionType, error) {
+	return r.types, nil
+}
+
+// rowList is a ProductRowReader over rows and parse errors given in advance.
+type rowList struct {
+	items []any // domain.ProductRow or domain.RowError
+}
+
+func (l *rowList) Next() (*domain.ProductRow, error) {
+	if len(l.items) == 0 {
+		return nil, io.EOF
+	}
+	item := l.items[0]
+	l.items = l.items[1:]
+	if rowErr, ok := item.(domain.RowError); ok {
+		return nil, &rowErr
+	}
+	row := item.(domain.ProductRow)
+	return &row, nil
+}
+
+func newImportService(catalog *memoryCatalog) (*ServiceImpl, *recordingUnitOfWork) {
+	uow := &recordingUnitOfWork{repos: catalog.repositories()}
+	s := &ServiceImpl{
+		optionTypeRepo: knownOptionTypes{types: []domain.OptionType{{Model: gorm.Model{ID: 1}, Name: "colour"}, {Model: gorm.Model{ID: 2}, Name: "size"}}},
+		uow:            uow,
+	}
+	return s, uow
+}
+
+func intPtr(v int) *int { return &v }
+
+func TestImportProductsDryRun(t *testing.T) {
+	for _, dryRun := range []bool{true, false} {
+		catalog := newMemoryCatalog()
+		s, uow := newImportService(catalog)
+		rows := &rowList{items: []any{
+			domain.ProductRow{Line: 2, SKU: "MUG", Name: "Mug", PriceCents: 999, Inventory: intPtr(8)},
+			domain.ProductRow{Line: 3, SKU: "TEE-RED-M", Name: "T-Shirt", PriceCents: 1999, Inventory: intPtr(4), Options: map[string]string{"colour": "red", "size": "M"}},
+			domain.ProductRow{Line: 4, SKU: "TEE-RED-L", Name: "T-Shirt", PriceCents: 1999, Options: map[string]string{"colour": "red", "size": "L"}},
+		}}
+
+		result, err := s.ImportProducts(7, rows, dryRun)
+		if err != nil {
+			t.Fatalf("dry run %v: %v", dryRun, err)
+		}
+		want := domain.ImportResult{Rows: 3, CreatedVariants: 2, UpdatedVariants: 1, CreatedProducts: 1, DryRun: dryRun, Committed: !dryRun}
+		if !reflect.DeepEqual(*result, want) {
+			t.Errorf("dry run %v: got %+v, want %+v", dryRun, *result, want)
+		}
+		if got := errors.Is(uow.err, errImportRolledBack); got != dryRun {
+			t.Errorf("dry run %v: transaction ended with %v", dryRun, uow.err)
+		}
+		// The dry run performs every write, so the rollback is all that differs
+		if len(catalog.variants) != 3 || len(catalog.movements) != 2 || catalog.products[1].PriceCents != 999 {
+			t.Errorf("dry run %v: writes were not applied: %+v", dryRun, catalog)
+		}
+	}
+}
+
+func TestImportProductsRowErrors(t *testing.T) {
+	catalog := newMemoryCatalog()
+	s, uow := newImportService(catalog)
+	rows := &rowList{items: []any{
+		domain.ProductRow{Line: 2, SKU: "TEE-RED-M", Name: "T-Shirt", PriceCents: 1999, Options: map[string]string{"colour": "red"}},
+		domain.RowError{Line: 3, Message: "price_cents must be a whole number"},
+		domain.ProductRow{Line: 4, SKU: "TEE-RED-M", Name: "T-Shirt", PriceCents: 1999},
+		domain.ProductRow{Line: 5, SKU: "CAP", ProductID: 99, Name: "Cap", PriceCents: 500},
+		domain.ProductRow{Line: 6, SKU: "HAT", Name: "Hat", PriceCents: 500, Options: map[string]string{"material": "wool"}},
+		domain.ProductRow{Line: 7, SKU: "MUG", ProductID: 2, Name: "Mug", PriceCents: 899},
+		domain.ProductRow{Line: 8, SKU: "BAG", Name: "", PriceCents: 500},
+	}}
+
+	result, err := s.ImportProducts(7, rows, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Committed || !errors.Is(uow.err, errImportRolledBack) {
+		t.Errorf("an import with row errors was committed: %+v, %v", result, uow.err)
+	}
+	if result.Rows != 7 || result.CreatedVariants != 1 || result.ErrorCount != 6 {
+		t.Errorf("got %+v", result)
+	}
+	var lines []int
+	for _, e := range result.Errors {
+		lines = append(lines, e.Line)
+	}
+	if !reflect.DeepEqual(lines, []int{3, 4, 5, 6, 7, 8}) {
+		t.Errorf("row errors on lines %v, want [3 4 5 6 7 8]", lines)
+	}
+}
+
+func TestImportProductsWithoutRows(t *testing.T) {
+	s, _ := newImportService(newMemoryCatalog())
+	if _, err := s.ImportProducts(7, &rowList{}, true); !errors.Is(err, domain.ErrInvalidImport) {
+		t.Errorf("got %v, want ErrInvalidImport", err)
+	}
+}
@@ -0,0 +1,178 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"ecommerce-api/domain"
+)
+
+// RefundOrder refunds the given lines of an order, or everything still refundable
+// when lines is empty. The refund is first recorded as pending against the locked
+// order, so a concurrent refund of the same lines fails before it reaches the
+// provider. The provider refund is then issued and the outcome, with the
+// optional restock, written back.
+func (s *ServiceImpl) RefundOrder(adminID uint, orderID uint, lines []domain.RefundLine, restock bool, reason string) (*domain.Refund, error) {
+	var order *domain.Order
+	var refund *domain.Refund
+	err := s.uow.Do(func(repos domain.Repositories) error {
+		var err error
+		if order, err = repos.Orders.FindByIDForUpdate(orderID); err != nil {
+			return err
+		}
+		if order.Status != domain.OrderStatusPaid && order.Status != domain.OrderStatusPartiallyRefunded {
+			return domain.ErrNotRefundable
+		}
+		if order.PaymentIntentID == "" {
+			return domain.ErrNotRefundable
+		}
+
+		if refund, err = buildRefund(order, lines); err != nil {
+			return err
+		}
+		refund.CreatedByID = adminID
+		refund.Reason = reason
+		refund.Restocked = restock
+		refund.Status = domain.RefundStatusPending
+		if err := repos.Refunds.Create(refund); err != nil {
+			return err
+		}
+		return applyRefund(repos, order, refund, 1)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	providerRefund, err := s.payments.Refund(order.PaymentIntentID, refund.AmountCents)
+	if errors.Is(err, domain.ErrPaymentTimeout) {
+		// The provider may still have refunded, so the lines stay claimed until someone checks
+		log.Printf("CRITICAL: provider refund for refund %d of order %d timed out; it stays pending until reconciled", refund.ID, order.ID)
+		return nil, fmt.Errorf("payment gateway failed to refund: %w", err)
+	}
+	if err != nil {
+		log.Printf("Payment provider refund failed for order %d: %v", order.ID, err)
+		releaseErr := s.uow.Do(func(repos domain.Repositories) error {
+			order, err := repos.Orders.FindByIDForUpdate(orderID)
+			if err != nil {
+				return err
+			}
+			refund.Status = domain.RefundStatusFailed
+			if err := repos.Refunds.Update(refund); err != nil {
+				return err
+			}
+			return applyRefund(repos, order, refund, -1)
+		})
+		if releaseErr != nil {
+			log.Printf("CRITICAL: failed refund %d of order %d could not be released: %v", refund.ID, order.ID, releaseErr)
+		}
+		return nil, fmt.Errorf("payment gateway failed to refund: %w", err)
+	}
+	refund.ProviderRefundID = providerRefund.ID
+	refund.Status = providerRefund.Status
+
+	err = s.uow.Do(func(repos domain.Repositories) error {
+		if err := repos.Refunds.Update(refund); err != nil {
+			return err
+		}
+		if restock {
+			for _, line := range refund.Items {
+				err := repos.Carts.UpdateInventory(line.VariantID, line.Quantity, domain.MovementRefundRestock, fmt.Sprintf("refund:%d", refund.ID))
+				if errors.Is(err, domain.ErrNotFound) {
+					// The variant was deleted since the sale; there is no stock left to return to
+					log.Printf("Refund %d: variant %d no longer exists, %d units not restocked", refund.ID, line.VariantID, line.Quantity)
+					continue
+				}
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		// The money has already left; this needs a human to reconcile
+		log.Printf("CRITICAL: provider refund %s for order %d succeeded but was not recorded: %v", refund.ProviderRefundID, order.ID, err)
+		return nil, errors.New("refund issued but could not be recorded")
+	}
+
+	return refund, nil
+}
+
+// applyRefund moves the order's refunded quantities and amount by the refund,
+// with sign 1 to claim them or -1 to give them back, and updates its status.
+func applyRefund(repos domain.Repositories, order *domain.Order, refund *domain.Refund, sign int) error {
+	for _, line := range refund.Items {
+		for i := range order.Items {
+			item := &order.Items[i]
+			if item.ID != line.OrderItemID {
+				continue
+			}
+			item.RefundedQuantity += sign * line.Quantity
+			if err := repos.Orders.UpdateItem(item); err != nil {
+				return err
+			}
+		}
+	}
+
+	order.RefundedCents += int64(sign) * refund.AmountCents
+	switch {
+	case order.RefundedCents >= order.TotalCents:
+		order.Status = domain.OrderStatusRefunded
+	case order.RefundedCents > 0:
+		order.Status = domain.OrderStatusPartiallyRefunded
+	default:
+		order.Status = domain.OrderStatusPaid
+	}
+	return repos.Orders.Update(order)
+}
+
+// buildRefund validates the requested lines against what is still refundable and
+// prices them at the snapshot unit price. Empty lines means a full refund.
+func buildRefund(order *domain.Order, lines []domain.RefundLine) (*domain.Refund, error) {
+	if len(lines) == 0 {
+		for _, item := range order.Items {
+			if remaining := item.Quantity - item.RefundedQuantity; remaining > 0 {
+				lines = append(lines, domain.RefundLine{OrderItemID: item.ID, Quantity: remaining})
+			}
+		}
+		if len(lines) == 0 {
+			return nil, domain.ErrNotRefundable
+		}
+	}
+
+	refund := &domain.Refund{OrderID: order.ID}
+	requested := make(map[uint]int)
+	for _, line := range lines {
+		if line.Quantity <= 0 {
+			return nil, fmt.Errorf("%w: quantity must be positive", domain.ErrInvalidRefund)
+		}
+
+		var item *domain.OrderItem
+		for i := range order.Items {
+			if order.Items[i].ID == line.OrderItemID {
+				item = &order.Items[i]
+				break
+			}
+		}
+		if item == nil {
+			return nil, fmt.Errorf("%w: item %d is not part of order %d", domain.ErrInvalidRefund, line.OrderItemID, order.ID)
+		}
+
+		requested[item.ID] += line.Quantity
+		if requested[item.ID] > item.Quantity-item.RefundedQuantity {
+			return nil, fmt.Errorf("%w: only %d of item %d can still be refunded", domain.ErrInvalidRefund, item.Quantity-item.RefundedQuantity, item.ID)
+		}
+
+		amount := item.PriceCents * int64(line.Quantity)
+		refund.AmountCents += amount
+		refund.Items = append(refund.Items, domain.RefundItem{
+			OrderItemID: item.ID,
+			ProductID:   item.ProductID,
+			VariantID:   item.VariantID,
+			Quantity:    line.Quantity,
+			AmountCents: amount,
+		})
+	}
+	return refund, nil
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"ecommerce-api/domain"
+)
+
+// ReleaseExpiredReservations settles orders whose stock reservation ran out before
+// the payment webhook arrived. The provider is asked first: a payment that did
+// succeed converts the stock, otherwise the intent is cancelled, the stock is
+// released and the order fails. It returns the number of orders settled.
+func (s *ServiceImpl) ReleaseExpiredReservations() (int, error) {
+	orderIDs, err := s.reservationRepo.FindExpiredOrderIDs(time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	settled := 0
+	for _, orderID := range orderIDs {
+		order, err := s.orderRepo.FindByID(orderID)
+		if err != nil {
+			log.Printf("Reservation sweep: could not load order %d: %v", orderID, err)
+			continue
+		}
+
+		next := domain.OrderStatusFailed
+		if order.PaymentIntentID != "" {
+			pi, err := s.payments.RetrieveIntent(order.PaymentIntentID)
+			if errors.Is(err, domain.ErrNotFound) {
+				// The provider has no such payment (the fake gateway forgets its intents on
+				// restart), so nothing can be paid for this order; let its reservation go
+				log.Printf("Reservation sweep: payment intent %s of order %d is unknown to the provider; failing the order", order.PaymentIntentID, order.ID)
+				pi = &PaymentIntent{ID: order.PaymentIntentID, Status: PaymentStatusCanceled}
+			} else if err != nil {
+				log.Printf("Reservation sweep: could not check payment intent %s: %v", order.PaymentIntentID, err)
+				continue // Try again on the next sweep rather than risk releasing paid stock
+			}
+			switch pi.Status {
+			case PaymentStatusSucceeded:
+				next = domain.OrderStatusPaid
+			case PaymentStatusProcessing:
+				continue // Still settling with the bank; keep holding the stock
+			case PaymentStatusCanceled:
+			default:
+				if _, err := s.payments.CancelIntent(order.PaymentIntentID); err != nil {
+					log.Printf("Reservation sweep: could not cancel payment intent %s: %v", order.PaymentIntentID, err)
+					continue
+				}
+			}
+		}
+
+		err = s.uow.Do(func(repos domain.Repositories) error {
+			if order.Status.CanTransitionTo(next) {
+				return applyOrderStatus(repos, order, next)
+			}
+			// The order already moved on (e.g. failed via webhook); just free what is left
+			return repos.Reservations.ReleaseForOrder(order.ID)
+		})
+		if err != nil {
+			log.Printf("Reservation sweep: could not settle order %d: %v", order.ID, err)
+			continue
+		}
+		settled++
+	}
+	return settled, nil
+}
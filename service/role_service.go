@@ -0,0 +1,73 @@
+package service
+
+import (
+	"fmt"
+
+	"ecommerce-api/domain"
+)
+
+// --- Roles ---
+
+func (s *ServiceImpl) ListRoles() ([]domain.Role, error) {
+	return s.roleRepo.FindAll()
+}
+
+// SetUserRoles replaces the user's roles. The change reaches the user's access
+// token on its next refresh. The granter may only add or remove roles whose
+// permissions they hold themselves, so users:manage cannot be used to escalate.
+func (s *ServiceImpl) SetUserRoles(granter *domain.Claims, userID uint, roleNames []string) (*domain.User, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	roles := []domain.Role{}
+	if len(roleNames) > 0 {
+		roles, err = s.roleRepo.FindByNames(roleNames)
+		if err != nil {
+			return nil, err
+		}
+	}
+	found := make(map[string]bool, len(roles))
+	for _, role := range roles {
+		found[role.Name] = true
+	}
+	for _, name := range roleNames {
+		if !found[name] {
+			return nil, fmt.Errorf("%w: %s", domain.ErrUnknownRole, name)
+		}
+	}
+
+	current := make(map[string]bool, len(user.Roles))
+	for _, role := range user.Roles {
+		current[role.Name] = true
+		if !found[role.Name] {
+			if err := checkGrantable(granter, role); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, role := range roles {
+		if !current[role.Name] {
+			if err := checkGrantable(granter, role); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := s.userRepo.ReplaceRoles(user, roles); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// checkGrantable reports ErrRoleNotGrantable unless granter holds every
+// permission of role.
+func checkGrantable(granter *domain.Claims, role domain.Role) error {
+	for _, rp := range role.Permissions {
+		if !granter.HasPermission(rp.Permission) {
+			return fmt.Errorf("%w: %s needs %q", domain.ErrRoleNotGrantable, role.Name, rp.Permission)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log"
+	"time"
+
+	"ecommerce-api/domain"
+)
+
+// IssueRefreshToken starts a new session for the user and returns its opaque
+// refresh token. mfa records whether the login passed a second factor.
+func (s *ServiceImpl) IssueRefreshToken(userID uint, mfa bool) (string, error) {
+	familyID, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+	token, _, err := s.newRefreshToken(s.refreshTokenRepo, userID, familyID, mfa)
+	return token, err
+}
+
+// RotateRefreshToken exchanges a refresh token for a new one and returns the
+// (freshly loaded) user it belongs to, and whether the session passed a second
+// factor that is still enabled. Presenting a token that was already rotated or
+// revoked is treated as theft: the whole session family is revoked.
+func (s *ServiceImpl) RotateRefreshToken(token string) (*domain.User, string, bool, error) {
+	stored, err := s.refreshTokenRepo.FindByHash(hashToken(token))
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, "", false, domain.ErrInvalidToken
+	}
+	if err != nil {
+		return nil, "", false, err
+	}
+
+	if stored.RevokedAt != nil {
+		s.revokeReusedFamily(stored)
+		return nil, "", false, domain.ErrTokenReused
+	}
+	if time.Now().After(stored.ExpiresAt) {
+		return nil, "", false, domain.ErrInvalidToken
+	}
+
+	// Reload the user so role changes and deletions take effect on refresh
+	user, err := s.userRepo.FindByID(stored.UserID)
+	if err != nil {
+		return nil, "", false, domain.ErrInvalidToken
+	}
+
+	var newToken string
+	err = s.uow.Do(func(repos domain.Repositories) error {
+		won, err := repos.RefreshTokens.Revoke(stored.ID)
+		if err != nil {
+			return err
+		}
+		if !won {
+			return domain.ErrTokenReused // A concurrent request rotated it first
+		}
+
+		var next *domain.RefreshToken
+		newToken, next, err = s.newRefreshToken(repos.RefreshTokens, stored.UserID, stored.FamilyID, stored.MFA)
+		if err != nil {
+			return err
+		}
+		revokedAt := time.Now()
+		stored.RevokedAt = &revokedAt
+		stored.ReplacedByID = &next.ID
+		return repos.RefreshTokens.Update(stored)
+	})
+	if errors.Is(err, domain.ErrTokenReused) {
+		s.revokeReusedFamily(stored)
+		return nil, "", false, err
+	}
+	if err != nil {
+		return nil, "", false, err
+	}
+	return user, newToken, stored.MFA && user.MFAEnabled(), nil
+}
+
+// RevokeRefreshToken ends the session the token belongs to. Unknown tokens are ignored.
+func (s *ServiceImpl) RevokeRefreshToken(token string) error {
+	stored, err := s.refreshTokenRepo.FindByHash(hashToken(token))
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return s.refreshTokenRepo.RevokeFamily(stored.FamilyID)
+}
+
+// RevokeAllSessions signs the user out everywhere.
+func (s *ServiceImpl) RevokeAllSessions(userID uint) error {
+	return s.refreshTokenRepo.RevokeAllForUser(userID)
+}
+
+func (s *ServiceImpl) newRefreshToken(repo domain.RefreshTokenRepository, userID uint, familyID string, mfa bool) (string, *domain.RefreshToken, error) {
+	token, err := randomToken()
+	if err != nil {
+		return "", nil, err
+	}
+	record := &domain.RefreshToken{
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(s.refreshTokenTTL),
+		MFA:       mfa,
+	}
+	if err := repo.Create(record); err != nil {
+		return "", nil, err
+	}
+	return token, record, nil
+}
+
+func (s *ServiceImpl) revokeReusedFamily(token *domain.RefreshToken) {
+	log.Printf("Refresh token reuse detected for user %d; revoking session family %s", token.UserID, token.FamilyID)
+	if err := s.refreshTokenRepo.RevokeFamily(token.FamilyID); err != nil {
+		log.Printf("Failed to revoke session family %s: %v", token.FamilyID, err)
+	}
+}
+
+// randomToken returns 256 bits of randomness, URL-safe encoded.
+func randomToken() (string, error) {
+	b, err := randomBytes(32)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken hashes high-entropy tokens for storage. A fast hash is enough here
+// because, unlike passwords, the tokens cannot be guessed.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
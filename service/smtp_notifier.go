@@ -0,0 +1,80 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"time"
+
+	"ecommerce-api/domain"
+)
+
+// SMTPConfig locates the mail server that delivers notifications.
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string // Optional; PLAIN auth is used when set
+	Password string
+	From     string // Sender address, e.g. "Shop <no-reply@example.com>"
+}
+
+// SMTPNotifier emails messages to the user's address. smtp.SendMail upgrades
+// the connection with STARTTLS when the server offers it, and refuses to send
+// credentials over a plain connection except to localhost.
+type SMTPNotifier struct {
+	cfg  SMTPConfig
+	from *mail.Address
+	send func(addr string, auth smtp.Auth, from string, to []string, msg []byte) error
+}
+
+func NewSMTPNotifier(cfg SMTPConfig) (*SMTPNotifier, error) {
+	if cfg.Host == "" {
+		return nil, fmt.Errorf("SMTP host is required")
+	}
+	from, err := mail.ParseAddress(cfg.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SMTP sender %q: %w", cfg.From, err)
+	}
+	return &SMTPNotifier{cfg: cfg, from: from, send: smtp.SendMail}, nil
+}
+
+func (n *SMTPNotifier) SendPasswordReset(user *domain.User, token string, expiresAt time.Time) error {
+	return n.sendMail(user, "Reset your password", fmt.Sprintf(
+		"Hello %s,\r\n\r\nUse this code to reset your password:\r\n\r\n%s\r\n\r\nIt expires at %s. If you did not ask for a reset, ignore this email.\r\n",
+		user.Username, token, expiresAt.UTC().Format(time.RFC1123)))
+}
+
+func (n *SMTPNotifier) SendEmailVerification(user *domain.User, token string, expiresAt time.Time) error {
+	return n.sendMail(user, "Confirm your email address", fmt.Sprintf(
+		"Hello %s,\r\n\r\nUse this code to confirm your email address:\r\n\r\n%s\r\n\r\nIt expires at %s.\r\n",
+		user.Username, token, expiresAt.UTC().Format(time.RFC1123)))
+}
+
+func (n *SMTPNotifier) sendMail(user *domain.User, subject, body string) error {
+	if user.Email == nil {
+		return domain.ErrEmailRequired
+	}
+	to, err := mail.ParseAddress(*user.Email)
+	if err != nil {
+		return fmt.Errorf("invalid recipient address: %w", err)
+	}
+
+	// Addresses are re-encoded by net/mail, so user input cannot add headers
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", n.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", to)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", subject)
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n")
+	msg.WriteString(body)
+
+	var auth smtp.Auth
+	if n.cfg.Username != "" {
+		auth = smtp.PlainAuth("", n.cfg.Username, n.cfg.Password, n.cfg.Host)
+	}
+	addr := net.JoinHostPort(n.cfg.Host, strconv.Itoa(n.cfg.Port))
+	return n.send(addr, auth, n.from.Address, []string{to.Address}, msg.Bytes())
+}
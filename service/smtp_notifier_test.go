@@ -0,0 +1,56 @@
+package service
+
+import (
+	"errors"
+	"net/smtp"
+	"strings"
+	"testing"
+	"time"
+
+	"ecommerce-api/domain"
+)
+
+func TestSMTPNotifierSendsToken(t *testing.T) {
+	n, err := NewSMTPNotifier(SMTPConfig{Host: "mail.example.com", Port: 587, From: "Shop <no-reply@example.com>"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var gotAddr, gotFrom string
+	var gotTo []string
+	var gotMsg string
+	n.send = func(addr string, auth smtp.Auth, from string, to []string, msg []byte) error {
+		gotAddr, gotFrom, gotTo, gotMsg = addr, from, to, string(msg)
+		return nil
+	}
+
+	email := "alice@example.com"
+	user := &domain.User{Username: "alice", Email: &email}
+	if err := n.SendPasswordReset(user, "reset-token-123", time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if gotAddr != "mail.example.com:587" || gotFrom != "no-reply@example.com" || len(gotTo) != 1 || gotTo[0] != email {
+		t.Errorf("sent to %s from %s for %v", gotAddr, gotFrom, gotTo)
+	}
+	if !strings.Contains(gotMsg, "\r\nTo: <alice@example.com>\r\n") || !strings.Contains(gotMsg, "reset-token-123") {
+		t.Errorf("unexpected message:\n%s", gotMsg)
+	}
+}
+
+func TestSMTPNotifierRejectsBadRecipients(t *testing.T) {
+	n, err := NewSMTPNotifier(SMTPConfig{Host: "mail.example.com", Port: 25, From: "no-reply@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.send = func(string, smtp.Auth, string, []string, []byte) error {
+		t.Error("message was sent")
+		return nil
+	}
+
+	if err := n.SendEmailVerification(&domain.User{Username: "bob"}, "token", time.Now()); !errors.Is(err, domain.ErrEmailRequired) {
+		t.Errorf("no email: got %v, want ErrEmailRequired", err)
+	}
+	injected := "bob@example.com\r\nBcc: eve@example.com"
+	if err := n.SendEmailVerification(&domain.User{Username: "bob", Email: &injected}, "token", time.Now()); err == nil {
+		t.Error("header injection: got nil error")
+	}
+}
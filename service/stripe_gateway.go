@@ -0,0 +1,192 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+	"strconv"
+
+	"github.com/stripe/stripe-go/v79"
+	"github.com/stripe/stripe-go/v79/client"
+	"github.com/stripe/stripe-go/v79/webhook"
+
+	"ecommerce-api/domain"
+)
+
+// stripeGateway is the PaymentGateway implementation using stripe-go.
+type stripeGateway struct {
+	sc *client.API
+}
+
+// NewStripeGateway initializes a Stripe client bound to the given secret key.
+func NewStripeGateway(key string) PaymentGateway {
+	sc := &client.API{}
+	sc.Init(key, nil)
+	return &stripeGateway{sc: sc}
+}
+
+// CreateIntent creates a new Payment Intent with Stripe.
+func (s *stripeGateway) CreateIntent(req PaymentIntentRequest) (*PaymentIntent, error) {
+	// Stripe requires amount in smallest currency unit (e.g., cents for USD)
+	params := &stripe.PaymentIntentParams{
+		Amount:   stripe.Int64(req.AmountCents),
+		Currency: stripe.String(req.Currency),
+		AutomaticPaymentMethods: &stripe.PaymentIntentAutomaticPaymentMethodsParams{
+			Enabled: stripe.Bool(true),
+		},
+		Description: stripe.String(req.Description),
+	}
+	// The order ID lets webhooks find the order even if they arrive before we store the intent ID
+	params.AddMetadata("order_id", strconv.FormatUint(uint64(req.OrderID), 10))
+	if req.IdempotencyKey != "" {
+		params.SetIdempotencyKey(req.IdempotencyKey)
+	}
+
+	pi, err := s.sc.PaymentIntents.New(params)
+	if err != nil {
+		return nil, stripeError(err)
+	}
+	return toPaymentIntent(pi), nil
+}
+
+func (s *stripeGateway) CaptureIntent(intentID string) (*PaymentIntent, error) {
+	pi, err := s.sc.PaymentIntents.Capture(intentID, nil)
+	if err != nil {
+		return nil, stripeError(err)
+	}
+	return toPaymentIntent(pi), nil
+}
+
+func (s *stripeGateway) CancelIntent(intentID string) (*PaymentIntent, error) {
+	pi, err := s.sc.PaymentIntents.Cancel(intentID, nil)
+	if err != nil {
+		return nil, stripeError(err)
+	}
+	return toPaymentIntent(pi), nil
+}
+
+func (s *stripeGateway) RetrieveIntent(intentID string) (*PaymentIntent, error) {
+	pi, err := s.sc.PaymentIntents.Get(intentID, nil)
+	if err != nil {
+		return nil, stripeError(err)
+	}
+	return toPaymentIntent(pi), nil
+}
+
+func (s *stripeGateway) Refund(intentID string, amountCents int64) (*PaymentRefund, error) {
+	params := &stripe.RefundParams{PaymentIntent: stripe.String(intentID)}
+	if amountCents > 0 {
+		params.Amount = stripe.Int64(amountCents)
+	}
+
+	refund, err := s.sc.Refunds.New(params)
+	if err != nil {
+		return nil, stripeError(err)
+	}
+	return &PaymentRefund{
+		ID:          refund.ID,
+		IntentID:    intentID,
+		AmountCents: refund.Amount,
+		Status:      string(refund.Status),
+	}, nil
+}
+
+func toPaymentIntent(pi *stripe.PaymentIntent) *PaymentIntent {
+	return &PaymentIntent{
+		ID:           pi.ID,
+		ClientSecret: pi.ClientSecret,
+		Status:       PaymentStatus(pi.Status),
+		AmountCents:  pi.Amount,
+		Currency:     string(pi.Currency),
+	}
+}
+
+// stripeError maps Stripe failures onto the domain payment errors the handlers understand.
+func stripeError(err error) error {
+	var stripeErr *stripe.Error
+	if errors.As(err, &stripeErr) {
+		if stripeErr.Type == stripe.ErrorTypeCard {
+			return errors.Join(domain.ErrPaymentDeclined, err)
+		}
+		if stripeErr.HTTPStatusCode == 404 {
+			return errors.Join(domain.ErrNotFound, err)
+		}
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return errors.Join(domain.ErrPaymentTimeout, err)
+	}
+	return err
+}
+
+// stripeWebhookVerifier checks Stripe-Signature headers against the endpoint's signing secret.
+type stripeWebhookVerifier struct {
+	secret string
+}
+
+// NewStripeWebhookVerifier returns a verifier for the given webhook signing secret.
+func NewStripeWebhookVerifier(secret string) WebhookVerifier {
+	return &stripeWebhookVerifier{secret: secret}
+}
+
+// ConstructWebhookEvent verifies the payload signature and maps the Stripe event
+// onto a domain.PaymentEvent. Event types we don't act on come back as PaymentEventIgnored.
+func (v *stripeWebhookVerifier) ConstructWebhookEvent(payload []byte, signature string) (*domain.PaymentEvent, error) {
+	if v.secret == "" {
+		log.Println("Rejecting Stripe webhook: STRIPE_WEBHOOK_SECRET is not configured")
+		return nil, domain.ErrInvalidSignature
+	}
+
+	// We only read stable fields (IDs, metadata), so events from older API versions are fine
+	event, err := webhook.ConstructEventWithOptions(payload, signature, v.secret, webhook.ConstructEventOptions{
+		IgnoreAPIVersionMismatch: true,
+	})
+	if err != nil {
+		return nil, domain.ErrInvalidSignature
+	}
+
+	result := &domain.PaymentEvent{
+		ID:           event.ID,
+		Type:         domain.PaymentEventIgnored,
+		ProviderType: string(event.Type),
+	}
+
+	switch event.Type {
+	case stripe.EventTypePaymentIntentSucceeded, stripe.EventTypePaymentIntentPaymentFailed:
+		var pi stripe.PaymentIntent
+		if err := json.Unmarshal(event.Data.Raw, &pi); err != nil {
+			return nil, err
+		}
+		result.PaymentIntentID = pi.ID
+		result.OrderID = orderIDFromMetadata(pi.Metadata)
+		if event.Type == stripe.EventTypePaymentIntentSucceeded {
+			result.Type = domain.PaymentEventSucceeded
+		} else {
+			result.Type = domain.PaymentEventFailed
+		}
+	case stripe.EventTypeChargeRefunded:
+		var charge stripe.Charge
+		if err := json.Unmarshal(event.Data.Raw, &charge); err != nil {
+			return nil, err
+		}
+		if charge.PaymentIntent != nil {
+			result.PaymentIntentID = charge.PaymentIntent.ID
+		}
+		result.OrderID = orderIDFromMetadata(charge.Metadata)
+		// Partial refunds also emit charge.refunded; only a full refund moves the order
+		if charge.Refunded {
+			result.Type = domain.PaymentEventRefunded
+		}
+	}
+
+	return result, nil
+}
+
+func orderIDFromMetadata(metadata map[string]string) uint {
+	id, err := strconv.ParseUint(metadata["order_id"], 10, 0)
+	if err != nil {
+		return 0
+	}
+	return uint(id)
+}
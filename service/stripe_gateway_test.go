@@ -0,0 +1,88 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stripe/stripe-go/v79/webhook"
+
+	"ecommerce-api/domain"
+)
+
+const testWebhookSecret = "whsec_test_secret"
+
+// signStripePayload builds a Stripe-Signature header for payload as Stripe would send it.
+func signStripePayload(payload []byte, secret string, at time.Time) string {
+	return fmt.Sprintf("t=%d,v1=%x", at.Unix(), webhook.ComputeSignature(at, payload, secret))
+}
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	payload, err := os.ReadFile(filepath.Join("testdata", "stripe", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return payload
+}
+
+func TestStripeWebhookVerifierEvents(t *testing.T) {
+	tests := []struct {
+		fixture string
+		want    domain.PaymentEvent
+	}{
+		{"payment_intent_succeeded.json", domain.PaymentEvent{ID: "evt_succeeded", Type: domain.PaymentEventSucceeded, ProviderType: "payment_intent.succeeded", PaymentIntentID: "pi_123", OrderID: 42}},
+		{"payment_intent_payment_failed.json", domain.PaymentEvent{ID: "evt_failed", Type: domain.PaymentEventFailed, ProviderType: "payment_intent.payment_failed", PaymentIntentID: "pi_456", OrderID: 43}},
+		{"charge_refunded.json", domain.PaymentEvent{ID: "evt_refunded", Type: domain.PaymentEventRefunded, ProviderType: "charge.refunded", PaymentIntentID: "pi_123", OrderID: 42}},
+		{"charge_partially_refunded.json", domain.PaymentEvent{ID: "evt_partial_refund", Type: domain.PaymentEventIgnored, ProviderType: "charge.refunded", PaymentIntentID: "pi_123", OrderID: 42}},
+		{"customer_created.json", domain.PaymentEvent{ID: "evt_customer", Type: domain.PaymentEventIgnored, ProviderType: "customer.created"}},
+	}
+
+	verifier := NewStripeWebhookVerifier(testWebhookSecret)
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			payload := readFixture(t, tt.fixture)
+			event, err := verifier.ConstructWebhookEvent(payload, signStripePayload(payload, testWebhookSecret, time.Now()))
+			if err != nil {
+				t.Fatalf("ConstructWebhookEvent: %v", err)
+			}
+			if *event != tt.want {
+				t.Errorf("event = %+v, want %+v", *event, tt.want)
+			}
+		})
+	}
+}
+
+func TestStripeWebhookVerifierRejects(t *testing.T) {
+	payload := readFixture(t, "payment_intent_succeeded.json")
+	tampered := bytes.Replace(payload, []byte(`"order_id": "42"`), []byte(`"order_id": "41"`), 1)
+	now := time.Now()
+
+	tests := []struct {
+		name      string
+		secret    string // Configured on the verifier
+		payload   []byte
+		signature string
+	}{
+		{"tampered payload", testWebhookSecret, tampered, signStripePayload(payload, testWebhookSecret, now)},
+		{"stale timestamp", testWebhookSecret, payload, signStripePayload(payload, testWebhookSecret, now.Add(-webhook.DefaultTolerance-time.Minute))},
+		{"wrong secret", testWebhookSecret, payload, signStripePayload(payload, "whsec_other_secret", now)},
+		{"missing signature", testWebhookSecret, payload, ""},
+		{"malformed signature", testWebhookSecret, payload, "t=not-a-time,v1=zz"},
+		{"no secret configured", "", payload, signStripePayload(payload, "", now)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verifier := NewStripeWebhookVerifier(tt.secret)
+			event, err := verifier.ConstructWebhookEvent(tt.payload, tt.signature)
+			if !errors.Is(err, domain.ErrInvalidSignature) {
+				t.Fatalf("err = %v, event = %+v; want ErrInvalidSignature", err, event)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package service
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestResizeToFit(t *testing.T) {
+	// A 4x2 image with a black left half and a white right half
+	src := image.NewGray(image.Rect(10, 10, 14, 12))
+	for y := 10; y < 12; y++ {
+		for x := 12; x < 14; x++ {
+			src.SetGray(x, y, color.Gray{Y: 255})
+		}
+	}
+	rgba := toRGBA(src)
+	if rgba.Rect != image.Rect(0, 0, 4, 2) {
+		t.Fatalf("toRGBA bounds = %v", rgba.Rect)
+	}
+
+	dst := resizeToFit(rgba, 2)
+	if dst.Rect != image.Rect(0, 0, 2, 1) {
+		t.Fatalf("resized bounds = %v, want 2x1", dst.Rect)
+	}
+	if got := dst.RGBAAt(0, 0); got != (color.RGBA{0, 0, 0, 255}) {
+		t.Errorf("left pixel = %v", got)
+	}
+	if got := dst.RGBAAt(1, 0); got != (color.RGBA{255, 255, 255, 255}) {
+		t.Errorf("right pixel = %v", got)
+	}
+
+	if same := resizeToFit(rgba, 4); same != rgba {
+		t.Error("an image that fits was copied")
+	}
+	if tall := resizeToFit(toRGBA(image.NewRGBA(image.Rect(0, 0, 300, 1200))), 800); tall.Rect != image.Rect(0, 0, 200, 800) {
+		t.Errorf("portrait bounds = %v, want 200x800", tall.Rect)
+	}
+}
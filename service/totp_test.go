@@ -0,0 +1,67 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA-1 seed from RFC 6238 Appendix B.
+var rfc6238Secret = []byte("12345678901234567890")
+
+// The RFC lists 8-digit codes; 6-digit codes are their last six digits.
+var rfc6238Vectors = []struct {
+	unix int64
+	code string
+}{
+	{59, "287082"},          // 94287082
+	{1111111109, "081804"},  // 07081804
+	{1111111111, "050471"},  // 14050471
+	{1234567890, "005924"},  // 89005924
+	{2000000000, "279037"},  // 69279037
+	{20000000000, "353130"}, // 65353130
+}
+
+func TestTOTPCodeRFC6238Vectors(t *testing.T) {
+	for _, tt := range rfc6238Vectors {
+		step := tt.unix / int64(totpPeriod.Seconds())
+		if got := totpCode(rfc6238Secret, step); got != tt.code {
+			t.Errorf("T=%d: got %s, want %s", tt.unix, got, tt.code)
+		}
+	}
+}
+
+func TestVerifyTOTP(t *testing.T) {
+	secret := totpEncoding.EncodeToString(rfc6238Secret)
+	for _, tt := range rfc6238Vectors {
+		now := time.Unix(tt.unix, 0)
+		step, ok := verifyTOTP(secret, tt.code, now)
+		if !ok || step != tt.unix/30 {
+			t.Errorf("T=%d: got step %d, %v", tt.unix, step, ok)
+		}
+	}
+
+	at := time.Unix(1111111111, 0) // Code 050471, step 37037037
+	tests := []struct {
+		name string
+		code string
+		now  time.Time
+		want bool
+	}{
+		{"one step late", "050471", at.Add(totpPeriod), true},
+		{"one step early", "050471", at.Add(-totpPeriod), true},
+		{"two steps late", "050471", at.Add(2 * totpPeriod), false},
+		{"wrong code", "050472", at, false},
+		{"8 digits", "14050471", at, false},
+		{"empty", "", at, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := verifyTOTP(secret, tt.code, tt.now); ok != tt.want {
+				t.Errorf("got %v, want %v", ok, tt.want)
+			}
+		})
+	}
+	if _, ok := verifyTOTP("not base32!", "050471", at); ok {
+		t.Error("accepted a malformed secret")
+	}
+}
@@ -1,10 +1,11 @@
 package service
 
 import (
-	"crypto/sha256"
-	"encoding/hex"
 	"errors"
+	"fmt"
 	"log"
+	"sync"
+	"time"
 
 	"ecommerce-api/domain"
 )
@@ -12,79 +13,335 @@ import (
 // ECommerceService defines all usecase operations for the application.
 type ECommerceService interface {
 	// Auth & User
-	Signup(username, password string) (*domain.User, error)
-	Login(username, password string) (*domain.User, error)
+	Signup(username, password, email string) (*domain.User, error)
+	Login(username, password, clientIP string) (*domain.User, error)
+	UnlockUser(userID uint) error
+
+	// External login (OIDC)
+	StartOIDCLogin(providerName string) (authURL string, state string, err error)
+	FinishOIDCLogin(providerName, state, code string) (*domain.User, error)
+
+	// Password reset
+	RequestPasswordReset(username string) error
+	ResetPassword(token, newPassword string) error
+
+	// Email verification
+	RequestEmailVerification(userID uint, email string) error
+	ConfirmEmail(token string) error
+
+	// Two-factor authentication
+	EnrollTOTP(userID uint) (secret string, uri string, err error)
+	ConfirmTOTP(userID uint, code string) ([]string, error)
+	DisableTOTP(userID uint, password, code string) error
+	VerifyMFA(userID uint, code, clientIP string) (*domain.User, error)
+
+	// Sessions
+	IssueRefreshToken(userID uint, mfa bool) (string, error)
+	RotateRefreshToken(token string) (*domain.User, string, bool, error)
+	RevokeRefreshToken(token string) error
+	RevokeAllSessions(userID uint) error
+
+	// Roles
+	ListRoles() ([]domain.Role, error)
+	SetUserRoles(granter *domain.Claims, userID uint, roleNames []string) (*domain.User, error)
+
+	// API keys
+	CreateAPIKey(creator *domain.Claims, name string, scopes []domain.Permission, expiresAt *time.Time) (*domain.APIKey, string, error)
+	ListAPIKeys() ([]domain.APIKey, error)
+	RevokeAPIKey(id uint) error
+	AuthenticateAPIKey(plaintext string) (*domain.Claims, error)
 
 	// Products
 	CreateProduct(product *domain.Product) error
-	GetProducts(query string) ([]domain.Product, error)
+	GetProducts(query domain.ProductQuery) (*domain.ProductPage, error)
+	GetProduct(id uint) (*domain.Product, error)
+	UpdateProduct(id uint, version int, changes domain.ProductChanges) (*domain.Product, error)
+	DeleteProduct(id uint) error
+	RestoreProduct(id uint) (*domain.Product, error)
+	SetProductCategories(productID uint, categoryIDs []uint) (*domain.Product, error)
+
+	// Variants
+	ListOptionTypes() ([]domain.OptionType, error)
+	CreateOptionType(name string) (*domain.OptionType, error)
+	CreateVariant(productID uint, variant *domain.ProductVariant) (*domain.Product, error)
+	UpdateVariant(productID uint, variantID uint, changes domain.VariantChanges) (*domain.Product, error)
+	DeleteVariant(productID uint, variantID uint) error
+
+	// Bulk import and export
+	ImportProducts(actorID uint, rows domain.ProductRowReader, dryRun bool) (*domain.ImportResult, error)
+	ExportProducts(fn func(row *domain.ProductRow) error) error
+
+	// Product images
+	UploadProductImage(productID uint, content []byte, declaredType, altText string) (*domain.ProductImage, error)
+	UpdateProductImage(productID uint, imageID uint, altText *string, position *int) (*domain.Product, error)
+	DeleteProductImage(productID uint, imageID uint) error
+	ImageURL(key string) string
+
+	// Categories
+	CategoryTree() ([]domain.Category, error)
+	CreateCategory(category *domain.Category) error
+	UpdateCategory(id uint, name, slug string, parentID *uint) (*domain.Category, error)
+	DeleteCategory(id uint) error
+
+	// Inventory
+	AdjustInventory(actorID uint, productID uint, variantID uint, quantity int, reason domain.MovementReason, note string) (*domain.InventoryMovement, error)
+	GetInventoryHistory(productID uint, limit int) ([]domain.InventoryMovement, error)
 
 	// Cart & Checkout
-	AddToCart(userID uint, productID uint, quantity int) (*domain.Cart, error)
-	RemoveFromCart(userID uint, productID uint, quantity int) (*domain.Cart, error)
+	AddToCart(userID uint, productID uint, variantID uint, quantity int) (*domain.Cart, error)
+	RemoveFromCart(userID uint, variantID uint, quantity int) (*domain.Cart, error)
 	ViewCart(userID uint) (*domain.Cart, error)
-	Checkout(userID uint) (map[string]interface{}, error)
+	Checkout(userID uint, idempotencyKey string) (map[string]interface{}, error)
+
+	// Orders
+	GetOrders(userID uint) ([]domain.Order, error)
+	GetOrder(userID uint, orderID uint) (*domain.Order, error)
+	HandlePaymentEvent(event *domain.PaymentEvent) error
+	RefundOrder(adminID uint, orderID uint, lines []domain.RefundLine, restock bool, reason string) (*domain.Refund, error)
+
+	// Stock reservations
+	ReleaseExpiredReservations() (int, error)
 }
 
 type ServiceImpl struct {
 	ECommerceService
-	userRepo    domain.UserRepository
-	productRepo domain.ProductRepository
-	cartRepo    domain.CartRepository
-	stripeSvc   StripeService
+	userRepo          domain.UserRepository
+	roleRepo          domain.RoleRepository
+	productRepo       domain.ProductRepository
+	variantRepo       domain.VariantRepository
+	optionTypeRepo    domain.OptionTypeRepository
+	productImageRepo  domain.ProductImageRepository
+	categoryRepo      domain.CategoryRepository
+	cartRepo          domain.CartRepository
+	orderRepo         domain.OrderRepository
+	reservationRepo   domain.ReservationRepository
+	inventoryRepo     domain.InventoryRepository
+	refreshTokenRepo  domain.RefreshTokenRepository
+	userTokenRepo     domain.UserTokenRepository
+	recoveryCodeRepo  domain.RecoveryCodeRepository
+	loginThrottleRepo domain.LoginThrottleRepository
+	identityRepo      domain.IdentityRepository
+	oidcStateRepo     domain.OIDCStateRepository
+	apiKeyRepo        domain.APIKeyRepository
+	uow               domain.UnitOfWork
+	payments          PaymentGateway
+	blobs             BlobStore
+	hasher            domain.PasswordHasher
+	notifier          Notifier
+
+	dummyHashOnce sync.Once
+	dummyHash     string // Verified against for unknown usernames, see spendPasswordCheck
+
+	maxImageBytes        int64
+	reservationTTL       time.Duration
+	refreshTokenTTL      time.Duration
+	passwordResetTTL     time.Duration
+	emailVerificationTTL time.Duration
+	requireVerifiedEmail bool
+	mfaIssuer            string
+	loginPolicy          LoginThrottlePolicy
+	oidcProviders        map[string]*OIDCProvider
 }
 
-func NewECommerceService(u domain.UserRepository, p domain.ProductRepository, c domain.CartRepository, s StripeService) ECommerceService {
-	return &ServiceImpl{userRepo: u, productRepo: p, cartRepo: c, stripeSvc: s}
+// Dependencies holds the repositories and external services used by ServiceImpl.
+type Dependencies struct {
+	Users         domain.UserRepository
+	Roles         domain.RoleRepository
+	Products      domain.ProductRepository
+	Variants      domain.VariantRepository
+	OptionTypes   domain.OptionTypeRepository
+	ProductImages domain.ProductImageRepository
+	Categories    domain.CategoryRepository
+	Carts         domain.CartRepository
+	Orders        domain.OrderRepository
+	Reservations  domain.ReservationRepository
+	Inventory     domain.InventoryRepository
+	RefreshTokens domain.RefreshTokenRepository
+	UserTokens    domain.UserTokenRepository
+	RecoveryCodes domain.RecoveryCodeRepository
+	LoginThrottle domain.LoginThrottleRepository
+	Identities    domain.IdentityRepository
+	OIDCStates    domain.OIDCStateRepository
+	APIKeys       domain.APIKeyRepository
+	UnitOfWork    domain.UnitOfWork
+	Payments      PaymentGateway
+	Blobs         BlobStore
+	Hasher        domain.PasswordHasher
+	Notifier      Notifier
+
+	// MaxImageBytes is the largest product image upload accepted.
+	MaxImageBytes int64
+	// ReservationTTL is how long checkout holds stock while waiting for payment.
+	ReservationTTL time.Duration
+	// RefreshTokenTTL is how long a session survives without being refreshed.
+	RefreshTokenTTL time.Duration
+	// PasswordResetTTL is how long a password reset token can be redeemed.
+	PasswordResetTTL time.Duration
+	// EmailVerificationTTL is how long an email verification token can be redeemed.
+	EmailVerificationTTL time.Duration
+	// RequireVerifiedEmail blocks checkout until the user has verified their email.
+	RequireVerifiedEmail bool
+	// MFAIssuer names the service in authenticator apps.
+	MFAIssuer string
+	// LoginPolicy limits failed logins per username and client IP.
+	LoginPolicy LoginThrottlePolicy
+	// OIDCProviders are the external identity providers users can log in with.
+	OIDCProviders []*OIDCProvider
 }
 
-// hashPassword is a simple utility (use bcrypt in production!)
-func hashPassword(password string) string {
-	h := sha256.New()
-	h.Write([]byte(password))
-	return hex.EncodeToString(h.Sum(nil))
+func NewECommerceService(d Dependencies) ECommerceService {
+	s := &ServiceImpl{
+		userRepo:          d.Users,
+		roleRepo:          d.Roles,
+		productRepo:       d.Products,
+		variantRepo:       d.Variants,
+		optionTypeRepo:    d.OptionTypes,
+		productImageRepo:  d.ProductImages,
+		categoryRepo:      d.Categories,
+		cartRepo:          d.Carts,
+		orderRepo:         d.Orders,
+		reservationRepo:   d.Reservations,
+		inventoryRepo:     d.Inventory,
+		refreshTokenRepo:  d.RefreshTokens,
+		userTokenRepo:     d.UserTokens,
+		recoveryCodeRepo:  d.RecoveryCodes,
+		loginThrottleRepo: d.LoginThrottle,
+		identityRepo:      d.Identities,
+		oidcStateRepo:     d.OIDCStates,
+		apiKeyRepo:        d.APIKeys,
+		uow:               d.UnitOfWork,
+		payments:          d.Payments,
+		blobs:             d.Blobs,
+		hasher:            d.Hasher,
+		notifier:          d.Notifier,
+
+		maxImageBytes:        d.MaxImageBytes,
+		reservationTTL:       d.ReservationTTL,
+		refreshTokenTTL:      d.RefreshTokenTTL,
+		passwordResetTTL:     d.PasswordResetTTL,
+		emailVerificationTTL: d.EmailVerificationTTL,
+		requireVerifiedEmail: d.RequireVerifiedEmail,
+		mfaIssuer:            d.MFAIssuer,
+		loginPolicy:          d.LoginPolicy,
+	}
+	s.oidcProviders = make(map[string]*OIDCProvider, len(d.OIDCProviders))
+	for _, p := range d.OIDCProviders {
+		s.oidcProviders[p.Name()] = p
+	}
+	return s
 }
 
 // --- Auth & User ---
 
-func (s *ServiceImpl) Signup(username, password string) (*domain.User, error) {
+// Signup creates an account. The email is optional; when given, a
+// verification token is sent to it.
+func (s *ServiceImpl) Signup(username, password, email string) (*domain.User, error) {
 	if username == "" || password == "" {
 		return nil, errors.New("username and password cannot be empty")
 	}
+	var emailPtr *string
+	if email != "" {
+		normalized, err := normalizeEmail(email)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := s.userRepo.FindByEmail(normalized); err == nil {
+			return nil, domain.ErrEmailTaken
+		}
+		emailPtr = &normalized
+	}
 
-	hashedPassword := hashPassword(password)
+	hashedPassword, err := s.hasher.Hash(password)
+	if err != nil {
+		return nil, err
+	}
 	user := &domain.User{
 		Username: username,
 		Password: hashedPassword,
-		IsAdmin:  false,
+		Email:    emailPtr,
 	}
 
 	if err := s.userRepo.Create(user); err != nil {
 		return nil, err // Handle unique constraint errors in handler/repository
 	}
+	if user.Email != nil {
+		if err := s.sendEmailVerification(user); err != nil {
+			log.Printf("Could not start email verification for user %d: %v", user.ID, err)
+		}
+	}
 	return user, nil
 }
 
-func (s *ServiceImpl) Login(username, password string) (*domain.User, error) {
+// Login checks the password. Failures count towards the brute-force limits of
+// both the username (known or not) and the client IP.
+func (s *ServiceImpl) Login(username, password, clientIP string) (*domain.User, error) {
+	if err := s.checkLoginThrottle(userSubject(username), ipSubject(clientIP)); err != nil {
+		return nil, err
+	}
+
 	user, err := s.userRepo.FindByUsername(username)
 	if err != nil {
+		s.spendPasswordCheck(password)
+		s.recordLoginFailure(username, clientIP)
 		return nil, domain.ErrInvalidCredentials // Hide specific error for security
 	}
 
-	hashedInput := hashPassword(password)
-	// In production, use bcrypt.CompareHashAndPassword(user.Password, []byte(password))
-	if user.Password != hashedInput {
+	ok, needsRehash, err := s.hasher.Verify(password, user.Password)
+	if err != nil {
+		log.Printf("Could not verify password hash of user %d: %v", user.ID, err)
+		s.recordLoginFailure(username, clientIP)
+		return nil, domain.ErrInvalidCredentials
+	}
+	if !ok {
+		s.recordLoginFailure(username, clientIP)
 		return nil, domain.ErrInvalidCredentials
 	}
+	// With 2FA the counter is only cleared once the second factor passes too
+	if !user.MFAEnabled() {
+		s.resetLoginThrottle(username)
+	}
+
+	// Upgrade legacy or weaker hashes now that we know the plaintext
+	if needsRehash {
+		if hashed, err := s.hasher.Hash(password); err != nil {
+			log.Printf("Warning: could not rehash password of user %d: %v", user.ID, err)
+		} else if err := s.userRepo.UpdatePassword(user.ID, hashed); err != nil {
+			log.Printf("Warning: could not store upgraded password hash of user %d: %v", user.ID, err)
+		} else {
+			user.Password = hashed
+		}
+	}
 	return user, nil
 }
 
-func (s *ServiceImpl) AddToCart(userID uint, productID uint, quantity int) (*domain.Cart, error) {
+// spendPasswordCheck verifies password against a throwaway hash, so a login
+// for an unknown username takes as long as one with a wrong password and the
+// response time does not reveal which usernames exist.
+func (s *ServiceImpl) spendPasswordCheck(password string) {
+	s.dummyHashOnce.Do(func() {
+		hash, err := s.hasher.Hash("not-a-real-password")
+		if err != nil {
+			log.Printf("Warning: could not create the dummy password hash: %v", err)
+		}
+		s.dummyHash = hash
+	})
+	if s.dummyHash != "" {
+		s.hasher.Verify(password, s.dummyHash)
+	}
+}
+
+// AddToCart adds units of a product variant to the user's cart. variantID may
+// be zero for a product with a single variant.
+func (s *ServiceImpl) AddToCart(userID uint, productID uint, variantID uint, quantity int) (*domain.Cart, error) {
 	product, err := s.productRepo.FindByID(productID)
 	if err != nil {
 		return nil, domain.ErrNotFound
 	}
-	if product.Inventory < quantity {
+	variant, err := chooseVariant(product, variantID)
+	if err != nil {
+		return nil, err
+	}
+	if variant.Available() < quantity {
 		return nil, domain.ErrInsufficientInv
 	}
 
@@ -92,7 +349,7 @@ func (s *ServiceImpl) AddToCart(userID uint, productID uint, quantity int) (*dom
 
 	found := false
 	for i, item := range cart.Items {
-		if item.ProductID == productID {
+		if item.VariantID == variant.ID {
 			cart.Items[i].Quantity += quantity
 			found = true
 			break
@@ -102,9 +359,11 @@ func (s *ServiceImpl) AddToCart(userID uint, productID uint, quantity int) (*dom
 	if !found {
 		cart.Items = append(cart.Items, domain.CartItem{
 			ProductID:  productID,
+			VariantID:  variant.ID,
+			SKU:        variant.SKU,
 			Quantity:   quantity,
-			Name:       product.Name,
-			PriceCents: product.PriceCents,
+			Name:       lineItemName(product, variant),
+			PriceCents: variant.Price(product.PriceCents),
 		})
 	}
 
@@ -115,7 +374,7 @@ func (s *ServiceImpl) AddToCart(userID uint, productID uint, quantity int) (*dom
 	return cart, nil
 }
 
-func (s *ServiceImpl) RemoveFromCart(userID uint, productID uint, quantity int) (*domain.Cart, error) {
+func (s *ServiceImpl) RemoveFromCart(userID uint, variantID uint, quantity int) (*domain.Cart, error) {
 	cart, err := s.cartRepo.FindByUserID(userID)
 	if err != nil {
 		return nil, domain.ErrNotFound
@@ -125,7 +384,7 @@ func (s *ServiceImpl) RemoveFromCart(userID uint, productID uint, quantity int)
 	removed := false
 
 	for _, item := range cart.Items {
-		if item.ProductID == productID {
+		if item.VariantID == variantID {
 			if item.Quantity > quantity {
 				item.Quantity -= quantity
 				newItems = append(newItems, item)
@@ -152,38 +411,204 @@ func (s *ServiceImpl) ViewCart(userID uint) (*domain.Cart, error) {
 	return s.cartRepo.FindByUserID(userID)
 }
 
-func (s *ServiceImpl) Checkout(userID uint) (map[string]interface{}, error) {
-	cart, err := s.cartRepo.FindByUserID(userID)
-	if err != nil {
-		return nil, domain.ErrNotFound
+// Checkout places an order for the user's cart and starts its payment. A non-empty
+// idempotencyKey (from the client's Idempotency-Key header) is stored on the order
+// and forwarded to the payment provider, so a retry with the same key returns the
+// same order and payment intent instead of placing another.
+func (s *ServiceImpl) Checkout(userID uint, idempotencyKey string) (map[string]interface{}, error) {
+	if s.requireVerifiedEmail {
+		user, err := s.userRepo.FindByID(userID)
+		if err != nil {
+			return nil, err
+		}
+		if !user.EmailVerified() {
+			return nil, domain.ErrEmailNotVerified
+		}
 	}
-	if len(cart.Items) == 0 {
-		return nil, domain.ErrCartEmpty
+
+	if idempotencyKey != "" {
+		order, err := s.orderRepo.FindByIdempotencyKey(userID, idempotencyKey)
+		if err == nil {
+			return s.resumeCheckout(order, idempotencyKey)
+		}
+		if !errors.Is(err, domain.ErrNotFound) {
+			return nil, err
+		}
 	}
 
-	var totalAmount int64
+	// The cart is read and cleared under its row lock, so concurrent checkouts
+	// cannot both order it and items added meanwhile are not lost. Stock is
+	// only reserved here; it is taken for good when the payment succeeds and
+	// released if the reservation expires unpaid.
+	var order *domain.Order
+	expiresAt := time.Now().Add(s.reservationTTL)
+	err := s.uow.Do(func(repos domain.Repositories) error {
+		cart, err := repos.Carts.FindByUserIDForUpdate(userID)
+		if errors.Is(err, domain.ErrNotFound) {
+			return domain.ErrCartEmpty
+		}
+		if err != nil {
+			return err
+		}
+		if len(cart.Items) == 0 {
+			return domain.ErrCartEmpty
+		}
 
-	for _, item := range cart.Items {
-		if err := s.cartRepo.UpdateInventory(item.ProductID, -item.Quantity); err != nil {
-			log.Printf("Inventory failure for product %d: %v", item.ProductID, err)
-			return nil, domain.ErrInsufficientInv
+		order = newOrder(userID, cart.Items, idempotencyKey)
+		if err := repos.Orders.Create(order); err != nil {
+			return err
+		}
+		for _, item := range order.Items {
+			err := repos.Reservations.Reserve(&domain.StockReservation{
+				ProductID: item.ProductID,
+				VariantID: item.VariantID,
+				Quantity:  item.Quantity,
+				CartID:    cart.ID,
+				OrderID:   order.ID,
+				ExpiresAt: expiresAt,
+			})
+			if err != nil {
+				log.Printf("Inventory failure for variant %d (%s): %v", item.VariantID, item.SKU, err)
+				return err
+			}
 		}
+		return repos.Carts.Clear(userID)
+	})
+	if err != nil {
+		if errors.Is(err, domain.ErrCartEmpty) || errors.Is(err, domain.ErrInsufficientInv) {
+			return nil, err
+		}
+		log.Printf("Checkout transaction failed for user %d: %v", userID, err)
+		return nil, errors.New("failed to place order")
+	}
+
+	return s.startPayment(order, idempotencyKey)
+}
+
+// newOrder builds a pending order for the cart items, snapshotting each price
+// and name so later catalog changes don't rewrite history.
+func newOrder(userID uint, items []domain.CartItem, idempotencyKey string) *domain.Order {
+	var totalAmount int64
+	orderItems := make([]domain.OrderItem, 0, len(items))
+	for _, item := range items {
 		totalAmount += item.PriceCents * int64(item.Quantity)
+		orderItems = append(orderItems, domain.OrderItem{
+			ProductID:  item.ProductID,
+			VariantID:  item.VariantID,
+			SKU:        item.SKU,
+			Name:       item.Name,
+			PriceCents: item.PriceCents,
+			Quantity:   item.Quantity,
+		})
+	}
+
+	order := &domain.Order{
+		UserID:     userID,
+		Status:     domain.OrderStatusPending,
+		TotalCents: totalAmount,
+		Currency:   "usd",
+		Items:      orderItems,
 	}
+	if idempotencyKey != "" {
+		order.IdempotencyKey = &idempotencyKey
+	}
+	return order
+}
 
-	pi, err := s.stripeSvc.CreatePaymentIntent(totalAmount, "usd", "E-commerce order from user")
+// resumeCheckout answers a retried checkout with the order its key placed,
+// starting the payment if the first attempt did not get that far.
+func (s *ServiceImpl) resumeCheckout(order *domain.Order, idempotencyKey string) (map[string]interface{}, error) {
+	if order.Status == domain.OrderStatusFailed {
+		return nil, domain.ErrCheckoutFailed
+	}
+	if order.PaymentIntentID == "" {
+		return s.startPayment(order, idempotencyKey)
+	}
+	pi, err := s.payments.RetrieveIntent(order.PaymentIntentID)
 	if err != nil {
+		log.Printf("Could not retrieve payment intent %s of order %d: %v", order.PaymentIntentID, order.ID, err)
+		return nil, errors.New("payment gateway failed to retrieve intent")
+	}
+	return checkoutResult(order, pi), nil
+}
+
+// startPayment creates the payment intent of a placed order. A declined
+// payment undoes the checkout. Other failures do the same without an
+// idempotency key; with one, the order is kept so a retry can resume it, and
+// the reservation sweeper fails it if no retry comes.
+func (s *ServiceImpl) startPayment(order *domain.Order, idempotencyKey string) (map[string]interface{}, error) {
+	intentReq := PaymentIntentRequest{
+		AmountCents: order.TotalCents,
+		Currency:    order.Currency,
+		Description: fmt.Sprintf("E-commerce order #%d", order.ID),
+		OrderID:     order.ID,
+	}
+	if idempotencyKey != "" {
+		// Provider keys are account-wide, so keep one user's key from colliding with another's
+		intentReq.IdempotencyKey = fmt.Sprintf("checkout-u%d-%s", order.UserID, idempotencyKey)
+	}
+	pi, err := s.payments.CreateIntent(intentReq)
+	if err != nil {
+		log.Printf("Payment intent failed for order %d: %v", order.ID, err)
+		if idempotencyKey == "" || errors.Is(err, domain.ErrPaymentDeclined) {
+			if cerr := s.compensateCheckout(order); cerr != nil {
+				log.Printf("CRITICAL: failed to roll back order %d after payment failure: %v", order.ID, cerr)
+			}
+		}
+		if errors.Is(err, domain.ErrPaymentDeclined) {
+			return nil, domain.ErrPaymentDeclined
+		}
+		if errors.Is(err, domain.ErrPaymentTimeout) {
+			return nil, domain.ErrPaymentTimeout
+		}
 		return nil, errors.New("payment gateway failed to create intent")
 	}
 
-	if err := s.cartRepo.Clear(userID); err != nil {
-		log.Printf("Warning: Payment succeeded but failed to clear cart for user %d: %v", userID, err)
+	order.PaymentIntentID = pi.ID
+	if err := s.orderRepo.Update(order); err != nil {
+		log.Printf("Warning: failed to attach payment intent %s to order %d: %v", pi.ID, order.ID, err)
 	}
+	return checkoutResult(order, pi), nil
+}
 
+func checkoutResult(order *domain.Order, pi *PaymentIntent) map[string]interface{} {
 	return map[string]interface{}{
 		"message":           "Checkout successful. Payment initiated.",
-		"total_paid_cents":  totalAmount,
+		"order_id":          order.ID,
+		"status":            order.Status,
+		"total_paid_cents":  order.TotalCents,
 		"payment_intent_id": pi.ID,
 		"client_secret":     pi.ClientSecret,
-	}, nil
+	}
+}
+
+// compensateCheckout undoes a checkout whose payment could not be started: the
+// reservations are released, the items go back into the cart and the order is marked failed.
+func (s *ServiceImpl) compensateCheckout(order *domain.Order) error {
+	return s.uow.Do(func(repos domain.Repositories) error {
+		if err := repos.Reservations.ReleaseForOrder(order.ID); err != nil {
+			return err
+		}
+
+		cart, err := repos.Carts.FindByUserID(order.UserID)
+		if err != nil {
+			return err
+		}
+		for _, item := range order.Items {
+			cart.Items = append(cart.Items, domain.CartItem{
+				ProductID:  item.ProductID,
+				VariantID:  item.VariantID,
+				SKU:        item.SKU,
+				Quantity:   item.Quantity,
+				Name:       item.Name,
+				PriceCents: item.PriceCents,
+			})
+		}
+		if err := repos.Carts.Save(cart); err != nil {
+			return err
+		}
+
+		order.Status = domain.OrderStatusFailed
+		return repos.Orders.Update(order)
+	})
 }
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"gorm.io/gorm"
+
+	"ecommerce-api/domain"
+)
+
+// lockedCart is a CartRepository holding one cart, which only the locking
+// read may load during checkout.
+type lockedCart struct {
+	domain.CartRepository
+	cart  *domain.Cart
+	locks int
+}
+
+func (r *lockedCart) FindByUserIDForUpdate(userID uint) (*domain.Cart, error) {
+	r.locks++
+	if r.cart.UserID != userID {
+		return nil, domain.ErrNotFound
+	}
+	cart := *r.cart
+	cart.Items = append([]domain.CartItem(nil), r.cart.Items...)
+	return &cart, nil
+}
+
+func (r *lockedCart) Clear(userID uint) error {
+	r.cart.Items = nil
+	return nil
+}
+
+type placedOrders struct {
+	domain.OrderRepository
+	orders []domain.Order
+}
+
+func (r *placedOrders) Create(order *domain.Order) error {
+	order.ID = uint(len(r.orders) + 1)
+	r.orders = append(r.orders, *order)
+	return nil
+}
+
+func (r *placedOrders) Update(order *domain.Order) error {
+	r.orders[order.ID-1] = *order
+	return nil
+}
+
+type acceptedReservations struct {
+	domain.ReservationRepository
+	reserved []domain.StockReservation
+}
+
+func (r *acceptedReservations) Reserve(res *domain.StockReservation) error {
+	r.reserved = append(r.reserved, *res)
+	return nil
+}
+
+func TestCheckoutOrdersTheLockedCart(t *testing.T) {
+	carts := &lockedCart{cart: &domain.Cart{Model: gorm.Model{ID: 3}, UserID: 9, Items: []domain.CartItem{
+		{ProductID: 1, VariantID: 10, SKU: "MUG", Name: "Mug", PriceCents: 899, Quantity: 2},
+		{ProductID: 2, VariantID: 20, SKU: "TEE", Name: "T-Shirt", PriceCents: 1999, Quantity: 1},
+	}}}
+	orders := &placedOrders{}
+	reservations := &acceptedReservations{}
+	s := &ServiceImpl{
+		orderRepo: orders,
+		payments:  NewFakeGateway(FakeScenarioSuccess),
+		uow: &recordingUnitOfWork{repos: domain.Repositories{
+			Carts:        carts,
+			Orders:       orders,
+			Reservations: reservations,
+		}},
+	}
+
+	result, err := s.Checkout(9, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result["total_paid_cents"] != int64(3797) || carts.locks != 1 {
+		t.Errorf("got %v after %d locked reads", result, carts.locks)
+	}
+	if len(reservations.reserved) != 2 || reservations.reserved[0].CartID != 3 {
+		t.Errorf("got reservations %+v", reservations.reserved)
+	}
+
+	// A second checkout finds the cart the first one cleared
+	if _, err := s.Checkout(9, ""); !errors.Is(err, domain.ErrCartEmpty) {
+		t.Errorf("second checkout: got %v, want ErrCartEmpty", err)
+	}
+	if _, err := s.Checkout(8, ""); !errors.Is(err, domain.ErrCartEmpty) {
+		t.Errorf("checkout without a cart: got %v, want ErrCartEmpty", err)
+	}
+	if len(orders.orders) != 1 {
+		t.Errorf("placed %d orders, want 1", len(orders.orders))
+	}
+}
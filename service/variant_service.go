@@ -0,0 +1,270 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"ecommerce-api/domain"
+)
+
+var skuPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+
+var errInvalidSKU = fmt.Errorf("%w: SKU must be 1 to 64 letters, digits, '.', '_' or '-'", domain.ErrInvalidVariant)
+
+const maxOptionTypeNameLength = 50
+
+// --- Option types ---
+
+func (s *ServiceImpl) ListOptionTypes() ([]domain.OptionType, error) {
+	return s.optionTypeRepo.FindAll()
+}
+
+// CreateOptionType adds an option type such as "size". Names are stored in lower case.
+func (s *ServiceImpl) CreateOptionType(name string) (*domain.OptionType, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" || len(name) > maxOptionTypeNameLength {
+		return nil, fmt.Errorf("%w: option type name must be 1 to %d characters", domain.ErrInvalidVariant, maxOptionTypeNameLength)
+	}
+	existing, err := s.optionTypeRepo.FindByNames([]string{name})
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) > 0 {
+		return nil, domain.ErrOptionTypeTaken
+	}
+
+	optionType := &domain.OptionType{Name: name}
+	if err := s.optionTypeRepo.Create(optionType); err != nil {
+		return nil, err
+	}
+	return optionType, nil
+}
+
+// --- Variants ---
+
+// CreateVariant adds a variant to the product and books its initial stock as
+// a receiving movement. Options name their type in OptionType.Name. It returns
+// the product with all its variants.
+func (s *ServiceImpl) CreateVariant(productID uint, variant *domain.ProductVariant) (*domain.Product, error) {
+	product, err := s.productRepo.FindByID(productID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.prepareVariant(variant, product.Variants); err != nil {
+		return nil, err
+	}
+	err = s.uow.Do(func(repos domain.Repositories) error {
+		return createVariant(repos, product.ID, variant, "Initial stock")
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.productRepo.FindByID(productID)
+}
+
+// UpdateVariant changes the variant's SKU or price override. Options are fixed
+// once created; add a new variant instead. Stock is changed through
+// AdjustInventory only. It returns the product with all its variants.
+func (s *ServiceImpl) UpdateVariant(productID uint, variantID uint, changes domain.VariantChanges) (*domain.Product, error) {
+	product, err := s.productRepo.FindByID(productID)
+	if err != nil {
+		return nil, err
+	}
+	variant := product.Variant(variantID)
+	if variant == nil {
+		return nil, domain.ErrNotFound
+	}
+
+	if changes.SKU != nil {
+		sku := strings.TrimSpace(*changes.SKU)
+		if sku != variant.SKU {
+			if err := s.checkSKU(sku); err != nil {
+				return nil, err
+			}
+			variant.SKU = sku
+		}
+	}
+	switch {
+	case changes.ClearPrice:
+		variant.PriceCents = nil
+	case changes.PriceCents != nil:
+		if *changes.PriceCents <= 0 {
+			return nil, fmt.Errorf("%w: price must be positive", domain.ErrInvalidVariant)
+		}
+		variant.PriceCents = changes.PriceCents
+	}
+
+	if err := s.variantRepo.Update(variant); err != nil {
+		return nil, err
+	}
+	return product, nil
+}
+
+// DeleteVariant removes a variant that holds no stock. The product's last
+// variant cannot be removed; delete the product instead.
+func (s *ServiceImpl) DeleteVariant(productID uint, variantID uint) error {
+	product, err := s.productRepo.FindByID(productID)
+	if err != nil {
+		return err
+	}
+	variant := product.Variant(variantID)
+	if variant == nil {
+		return domain.ErrNotFound
+	}
+	if len(product.Variants) == 1 {
+		return domain.ErrLastVariant
+	}
+	return s.variantRepo.Delete(variant.ID)
+}
+
+// prepareVariant validates a new variant against the product's other variants
+// and resolves its option types by name.
+func (s *ServiceImpl) prepareVariant(variant *domain.ProductVariant, siblings []domain.ProductVariant) error {
+	names, err := checkVariant(variant)
+	if err != nil {
+		return err
+	}
+	for _, sibling := range siblings {
+		if sibling.SKU == variant.SKU {
+			return domain.ErrSKUTaken
+		}
+	}
+	if err := s.checkSKU(variant.SKU); err != nil {
+		return err
+	}
+
+	var optionTypes []domain.OptionType
+	if len(names) > 0 {
+		if optionTypes, err = s.optionTypeRepo.FindByNames(names); err != nil {
+			return err
+		}
+	}
+	return resolveOptions(variant, siblings, optionTypes)
+}
+
+// checkVariant validates the fields of a new variant and normalizes its
+// options, returning their type names.
+func checkVariant(variant *domain.ProductVariant) ([]string, error) {
+	variant.ID = 0
+	variant.Reserved = 0
+	variant.SKU = strings.TrimSpace(variant.SKU)
+	switch {
+	case variant.PriceCents != nil && *variant.PriceCents <= 0:
+		return nil, fmt.Errorf("%w: price must be positive", domain.ErrInvalidVariant)
+	case variant.Inventory < 0:
+		return nil, fmt.Errorf("%w: inventory must not be negative", domain.ErrInvalidVariant)
+	case !skuPattern.MatchString(variant.SKU):
+		return nil, errInvalidSKU
+	}
+
+	names := make([]string, 0, len(variant.Options))
+	seen := make(map[string]bool, len(variant.Options))
+	for i := range variant.Options {
+		option := &variant.Options[i]
+		option.OptionType.Name = strings.ToLower(strings.TrimSpace(option.OptionType.Name))
+		option.Value = strings.TrimSpace(option.Value)
+		if option.OptionType.Name == "" || option.Value == "" {
+			return nil, fmt.Errorf("%w: options need a type and a value", domain.ErrInvalidVariant)
+		}
+		if seen[option.OptionType.Name] {
+			return nil, fmt.Errorf("%w: option %q is given twice", domain.ErrInvalidVariant, option.OptionType.Name)
+		}
+		seen[option.OptionType.Name] = true
+		names = append(names, option.OptionType.Name)
+	}
+	return names, nil
+}
+
+// resolveOptions points the variant's options at their types and rejects a
+// variant whose options match one of its siblings.
+func resolveOptions(variant *domain.ProductVariant, siblings []domain.ProductVariant, optionTypes []domain.OptionType) error {
+	byName := make(map[string]domain.OptionType, len(optionTypes))
+	for _, t := range optionTypes {
+		byName[t.Name] = t
+	}
+	for i := range variant.Options {
+		option := &variant.Options[i]
+		t, ok := byName[option.OptionType.Name]
+		if !ok {
+			return fmt.Errorf("%w: unknown option type %q", domain.ErrInvalidVariant, option.OptionType.Name)
+		}
+		option.ID = 0
+		option.OptionTypeID = t.ID
+		option.OptionType = t
+	}
+
+	label := variant.Label()
+	for _, sibling := range siblings {
+		if sibling.Label() == label {
+			return fmt.Errorf("%w: another variant already has these options", domain.ErrInvalidVariant)
+		}
+	}
+	return nil
+}
+
+// checkSKU validates the format of a SKU and that no other variant uses it.
+func (s *ServiceImpl) checkSKU(sku string) error {
+	if !skuPattern.MatchString(sku) {
+		return errInvalidSKU
+	}
+	_, err := s.variantRepo.FindBySKU(sku)
+	switch {
+	case err == nil:
+		return domain.ErrSKUTaken
+	case errors.Is(err, domain.ErrNotFound):
+		return nil
+	default:
+		return err
+	}
+}
+
+// createVariant stores the variant for the product and books its Inventory
+// through the ledger, so the product's totals follow.
+func createVariant(repos domain.Repositories, productID uint, variant *domain.ProductVariant, note string) error {
+	stock := variant.Inventory
+	variant.ProductID = productID
+	variant.Inventory = 0
+	variant.Reserved = 0
+	if err := repos.Variants.Create(variant); err != nil {
+		return err
+	}
+	if stock > 0 {
+		err := repos.Inventory.Record(&domain.InventoryMovement{
+			VariantID:      variant.ID,
+			Reason:         domain.MovementReceiving,
+			InventoryDelta: stock,
+			Note:           note,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	variant.Inventory = stock
+	return nil
+}
+
+// chooseVariant returns the product's variant with the given ID. A zero ID
+// picks the only variant of a product that has just one.
+func chooseVariant(product *domain.Product, variantID uint) (*domain.ProductVariant, error) {
+	if variantID == 0 {
+		if len(product.Variants) == 1 {
+			return &product.Variants[0], nil
+		}
+		return nil, domain.ErrVariantRequired
+	}
+	variant := product.Variant(variantID)
+	if variant == nil {
+		return nil, domain.ErrNotFound
+	}
+	return variant, nil
+}
+
+// lineItemName names a cart or order line after the product and the variant's options.
+func lineItemName(product *domain.Product, variant *domain.ProductVariant) string {
+	if label := variant.Label(); label != "" {
+		return fmt.Sprintf("%s (%s)", product.Name, label)
+	}
+	return product.Name
+}